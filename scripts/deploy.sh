@@ -1,4 +1,10 @@
 #!/usr/bin/env bash
 
+# Serverless resolves a missing environment variable to an empty one, which would deploy unsigned cursors.
+if [ -z "$CURSOR_SECRET" ] ; then
+  echo "✕ CURSOR_SECRET is not set, export a long random value before deploying."
+  exit 1
+fi
+
 ./scripts/build.sh
-serverless deploy
\ No newline at end of file
+serverless deploy
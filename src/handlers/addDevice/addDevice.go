@@ -1,20 +1,53 @@
 package main
 
 import (
+	"actor"
+	"clock"
+	"crypto/sha256"
+	"devicemodels"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"headers"
+	"ids"
+	"lifecycle"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 	"types"
+	"versioning"
 )
 
+// Header which clients send to make retries of the same request safe.
+const IdempotencyHeader = "Idempotency-Key"
+
+// How long a response is kept for replaying. DynamoDB removes expired records by its TTL.
+const IdempotencyTTL = 24 * time.Hour
+
+// Longest Idempotency-Key accepted from clients.
+const MaxIdempotencyKeyLength = 255
+
+// Struct containing a stored response for an Idempotency-Key, for marshalling/unmarshalling.
+// A record without status code is reserved by a request which is still being executed.
+type IdempotencyRecord struct {
+	Key         string            `json:"key"`
+	RequestHash string            `json:"requestHash"`
+	StatusCode  int               `json:"statusCode,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	ExpiresAt   int64             `json:"expiresAt"`
+}
+
 type AmazonWebServices struct {
 	Config   *aws.Config
 	Session  *session.Session
@@ -41,23 +74,221 @@ func init() {
 	TestAws = Aws
 }
 
-// Preparing DynamoDB Session and Calling DB's PutItem function inside.
-func (self *AmazonWebServices) Put(item map[string]*dynamodb.AttributeValue) (*dynamodb.PutItemOutput, error) {
+// Preparing DynamoDB Session and Calling DB's TransactWriteItems function inside, through devicemodels.Write.
+// Adding is create-only, an existing item with the same id is never overwritten. The device model is checked in the
+// same transaction, so it can not be deleted while the device is added.
+func (self *AmazonWebServices) Put(device types.Device) (*dynamodb.TransactWriteItemsOutput, error) {
 	// Get table name from OS's environment
 	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
-	var input = &dynamodb.PutItemInput{
-		Item:      item,
+
+	// Serialization/Encoding "device" in "item" for using in DynamoDB functions.
+	item, err := dynamodbattribute.MarshalMap(device)
+	if err != nil {
+		return nil, err
+	}
+
+	var write = &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			Item:                item,
+			TableName:           tableName,
+			ConditionExpression: aws.String("attribute_not_exists(id)"),
+		},
+	}
+	// Calling either TransactWriteItems function of interface, defined in addDevice_test.go file, or api with the input we've provided.
+	// In real deployment environment, the TransactWriteItems function of aws (api.go) will be called.
+	result, err := devicemodels.Write(self.DynamoDB, write, device)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside, on the idempotency table.
+func (self *AmazonWebServices) GetIdempotencyRecord(key string) (*dynamodb.GetItemOutput, error) {
+	// Get table name from OS's environment
+	tableName := aws.String(os.Getenv("IDEMPOTENCY_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
 		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"key": {
+				S: aws.String(key),
+			},
+		},
+		// A retry right after the first request has to see its record.
+		ConsistentRead: aws.Bool(true),
 	}
+
+	// Calling either GetItem function of interface, defined in addDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's PutItem function inside, on the idempotency table.
+// Without a status code the record only reserves the key. If the key is already taken and has not expired, the condition fails.
+// With a status code the response is stored for replaying, over the reservation of the same request.
+func (self *AmazonWebServices) PutIdempotencyRecord(record IdempotencyRecord) (*dynamodb.PutItemOutput, error) {
+	// Get table name from OS's environment
+	tableName := aws.String(os.Getenv("IDEMPOTENCY_TABLE_NAME"))
+
+	// Serialization/Encoding "record" in "item" for using in DynamoDB functions.
+	item, _ := dynamodbattribute.MarshalMap(record)
+
+	var input = &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           tableName,
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR expiresAt < :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key": aws.String("key"),
+		},
+		// DynamoDB removes expired records up to two days late, until then they are taken over like missing ones.
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {
+				N: aws.String(strconv.FormatInt(clock.Now().Unix(), 10)),
+			},
+		},
+	}
+	if record.StatusCode != 0 {
+		input.ConditionExpression = aws.String("requestHash = :requestHash")
+		input.ExpressionAttributeNames = nil
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":requestHash": {
+				S: aws.String(record.RequestHash),
+			},
+		}
+	}
+
 	// Calling either PutItem function of interface, defined in addDevice_test.go file, or api with the input we've provided.
-	// In mock case, the PutItem function of getDeviceById_test.go will be called(interface.go)
-	// In real deployment environment, the PutItem function of aws (api.go) will be called.
 	result, err := self.DynamoDB.PutItem(input)
 	return result, err
 }
 
+// Preparing DynamoDB Session and Calling DB's DeleteItem function inside, on the idempotency table.
+// Releasing the key lets a retry execute the request again.
+func (self *AmazonWebServices) DeleteIdempotencyRecord(key string) (*dynamodb.DeleteItemOutput, error) {
+	// Get table name from OS's environment
+	tableName := aws.String(os.Getenv("IDEMPOTENCY_TABLE_NAME"))
+
+	var input = &dynamodb.DeleteItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"key": {
+				S: aws.String(key),
+			},
+		},
+	}
+
+	// Calling either DeleteItem function of interface, defined in addDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.DeleteItem(input)
+	return result, err
+}
+
 // The handler function which will be first started from main function.
+// Requests with an Idempotency-Key are executed once, and their response is replayed for every retry.
 func AddDevice(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	key := headers.Get(request.Headers, IdempotencyHeader)
+	if key == "" {
+		return CreateDevice(request)
+	}
+
+	if len(key) > MaxIdempotencyKeyLength {
+		return events.APIGatewayProxyResponse{
+			Body:       fmt.Sprintf("Wrong format: %s must not be longer than %d characters.", IdempotencyHeader, MaxIdempotencyKeyLength),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// Keys are scoped to this endpoint, so the same key on another endpoint is another request.
+	record := IdempotencyRecord{
+		Key:         "addDevice#" + key,
+		RequestHash: HashRequest(request),
+		ExpiresAt:   clock.Now().Add(IdempotencyTTL).Unix(),
+	}
+
+	// Reserve the key before executing the request, so concurrent retries can not execute it twice.
+	_, err := TestAws.PutIdempotencyRecord(record)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ReplayResponse(record)
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	response, err := CreateDevice(request)
+
+	// Server errors are not final, release the key so a retry can execute the request again.
+	if err != nil || response.StatusCode >= 500 {
+		TestAws.DeleteIdempotencyRecord(record.Key)
+		return response, err
+	}
+
+	// Store the response for replaying. If it can not be stored, the key stays reserved until it expires,
+	// but the device has been created, so the response is still returned.
+	record.StatusCode = response.StatusCode
+	record.Headers = response.Headers
+	record.Body = response.Body
+	if _, err = TestAws.PutIdempotencyRecord(record); err != nil {
+		fmt.Println(fmt.Sprintf("Failed to store response of %s: %s", record.Key, err.Error()))
+	}
+
+	return response, nil
+} // End of AddDevice function
+
+// Returns the stored response of an Idempotency-Key which has been used before.
+func ReplayResponse(record IdempotencyRecord) (events.APIGatewayProxyResponse, error) {
+	result, err := TestAws.GetIdempotencyRecord(record.Key)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// The record has expired or was released between reserving and reading it, the client has to retry.
+	// Expired records which DynamoDB has not removed yet are treated as missing ones.
+	StoredRecord := IdempotencyRecord{}
+	dynamodbattribute.UnmarshalMap(result.Item, &StoredRecord)
+	if len(result.Item) == 0 || StoredRecord.ExpiresAt <= clock.Now().Unix() || (StoredRecord.StatusCode == 0 && StoredRecord.RequestHash == record.RequestHash) {
+		jsonResponse, _ := json.Marshal(types.Error{
+			Code:    "RequestInProgress",
+			Message: "A request with this " + IdempotencyHeader + " is being executed, retry later.",
+		})
+		return events.APIGatewayProxyResponse{
+			Body:       string(jsonResponse),
+			Headers:    map[string]string{"Content-Type": "application/json", "Retry-After": "1"},
+			StatusCode: 409,
+		}, nil
+	}
+
+	// The same key with another body is a client bug, return HTTP error code 422.
+	if StoredRecord.RequestHash != record.RequestHash {
+		jsonResponse, _ := json.Marshal(types.Error{
+			Code:    "IdempotencyKeyReused",
+			Message: "This " + IdempotencyHeader + " has been used with another request body.",
+		})
+		return events.APIGatewayProxyResponse{
+			Body:       string(jsonResponse),
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			StatusCode: 422,
+		}, nil
+	}
+
+	// Same request again, return the stored response as it was.
+	return events.APIGatewayProxyResponse{
+		Body:       StoredRecord.Body,
+		Headers:    StoredRecord.Headers,
+		StatusCode: StoredRecord.StatusCode,
+	}, nil
+} // End of ReplayResponse function
+
+// Fingerprint of the request body, for telling apart retries from other requests with the same key.
+func HashRequest(request events.APIGatewayProxyRequest) string {
+	hash := sha256.Sum256([]byte(request.Body))
+	return hex.EncodeToString(hash[:])
+}
+
+// Adds the device from the request body.
+func CreateDevice(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// First & foremost we have to validate user input.
 	NewDevice, err := ValidateInputs(request)
 	// if inputs are not suitable, return HTTP error code 400.
@@ -68,12 +299,52 @@ func AddDevice(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRes
 		}, nil
 	}
 
-	// Serialization/Encoding "NewDevice" in "item" for using in DynamoDB functions.
-	item, _ := dynamodbattribute.MarshalMap(NewDevice)
+	// The device model has to exist, so typos do not create phantom models.
+	if err = devicemodels.Check(TestAws.DynamoDB, NewDevice); err != nil {
+		if devicemodels.IsClientError(err) {
+			return events.APIGatewayProxyResponse{
+				Body:       err.Error(),
+				StatusCode: 400,
+			}, nil
+		}
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Every device starts with the first version, in the first state of its lifecycle.
+	NewDevice.Version = 1
+	NewDevice.Status = lifecycle.Initial
+	// Stamped with when and by whom it has been added, for auditing and the history of the device.
+	NewDevice = actor.New(NewDevice, actor.From(request))
+	// Kept for looking up the device by its serial.
+	NewDevice.SerialKey = types.NormalizeSerial(NewDevice.Serial)
 
 	// Till now the user have provided a valid data input.
 	// Let's add it to the DynamoDB table.
-	_, err = TestAws.Put(item)
+	_, err = TestAws.Put(NewDevice)
+
+	// There is already a device with this id, even if it's deleted. Return HTTP error code 409.
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		jsonResponse, _ := json.Marshal(types.Error{
+			Code:    "DeviceAlreadyExists",
+			Message: "A device with id " + NewDevice.ID + " already exists, use PUT or PATCH to change it.",
+		})
+		return events.APIGatewayProxyResponse{
+			Body:       string(jsonResponse),
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			StatusCode: 409,
+		}, nil
+	}
+
+	// The device model has been deleted since it was checked, or is being deleted. Return HTTP error code 400.
+	if devicemodels.IsClientError(err) {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
 
 	// If internal database errors occurred, return HTTP error code 500.
 	if err != nil {
@@ -86,52 +357,38 @@ func AddDevice(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRes
 	// Serialization/Encoding "NewDevice" to JSON.
 	jsonResponse, _ := json.Marshal(NewDevice)
 	return events.APIGatewayProxyResponse{
-		Body: string(jsonResponse),
+		Body:    string(jsonResponse),
+		Headers: map[string]string{"ETag": versioning.ETag(NewDevice.Version), "Location": Location(request, NewDevice.ID)},
 		// Everything looks fine, return HTTP 201
 		StatusCode: 201,
 	}, nil
-} // End of AddDevice function
-
-func ValidateInputs(request events.APIGatewayProxyRequest) (types.Device, error) {
-	NewDevice := types.Device{}
-	ErrorMessage := ""
+} // End of CreateDevice function
 
-	if len(request.Body) == 0 {
-		ErrorMessage = "No inputs provided, please provide inputs in JSON format."
-		return types.Device{}, errors.New(ErrorMessage)
+// Returns the URL which the added device can be read from, under the devices resource it has been added to.
+// API Gateway's path has the stage in it, i.e: "/dev/devices" gives "/dev/devices/dev_01DXJ3BK48MPJTB9D5MPJTB9D5".
+func Location(request events.APIGatewayProxyRequest, id string) string {
+	path := request.RequestContext.Path
+	if path == "" {
+		path = request.Path
 	}
+	return strings.TrimSuffix(path, "/") + "/" + url.PathEscape(id)
+}
 
+func ValidateInputs(request events.APIGatewayProxyRequest) (types.Device, error) {
 	// De-serialize "request.Body" which is in JSON format into "NewDevice" in Go object.
-	var err = json.Unmarshal([]byte(request.Body), &NewDevice)
-
+	NewDevice, err := types.ParseDevice(request.Body)
 	if err != nil {
-		ErrorMessage = "Wrong format: Inputs must be a valid JSON."
-		return types.Device{}, errors.New(ErrorMessage)
-	}
-
-	if len(NewDevice.ID) == 0 {
-		ErrorMessage = "Missing field: ID"
-		return types.Device{}, errors.New(ErrorMessage)
-	}
-
-	if len(NewDevice.DeviceModel) == 0 {
-		ErrorMessage = "Missing field: Device Model"
-		return types.Device{}, errors.New(ErrorMessage)
-	}
-
-	if len(NewDevice.Name) == 0 {
-		ErrorMessage = "Missing field: Name"
-		return types.Device{}, errors.New(ErrorMessage)
+		return types.Device{}, err
 	}
 
-	if len(NewDevice.Note) == 0 {
-		ErrorMessage = "Missing field: Note"
-		return types.Device{}, errors.New(ErrorMessage)
+	// Ids are generated by the server, unless clients are allowed to choose them.
+	if NewDevice, err = ids.Assign(NewDevice); err != nil {
+		return types.Device{}, err
 	}
 
-	if len(NewDevice.Serial) == 0 {
-		ErrorMessage = "Missing field: Serial"
-		return types.Device{}, errors.New(ErrorMessage)
+	// Rules for the fields are shared with the other write handlers.
+	if err = NewDevice.Validate(); err != nil {
+		return types.Device{}, err
 	}
 
 	// Everything looks fine, return created NewDevice in Go struct.
@@ -1,17 +1,26 @@
 package main
 
 import (
+	"clock"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"ids"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"types"
 )
 
 type TestCase struct {
 	Name                   string
 	Request                events.APIGatewayProxyRequest
-	inputedItems           map[string]*dynamodb.AttributeValue
+	inputedDevice          types.Device
 	ExpectedBody           string
 	ExpectedStatusCode     int
 	ExpectedDatabaseOutput dynamodb.PutItemOutput
@@ -21,52 +30,222 @@ type TestCase struct {
 // Mocking DynamoDB through dynamodbiface.
 type MockDynamoDB struct {
 	dynamodbiface.DynamoDBAPI
-	// Other return values expected to store, i.e: "payload map[string]string" or "err error"
+	// Last input the mocked PutItem function has received.
+	Input *dynamodb.PutItemInput
+	// Items of the mocked idempotency table, by their key.
+	IdempotencyRecords map[string]map[string]*dynamodb.AttributeValue
+	// Number of devices the mocked PutItem function has written.
+	Writes int
 }
 
 // Custom PutItem function for overriding the PutItem of getDeviceById.go for using in test scenarios.
-// Mocking PutItem output to the a desire valid response.
+// Mocking PutItem output to the a desire valid response. "id_test" already exists on the mocked DB.
 func (self *MockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	if *input.TableName == "idempotency_test" {
+		return self.PutIdempotencyItem(input)
+	}
+
+	self.Input = input
+	self.Writes++
+	if *input.Item["id"].S == "id_test" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
 	MockOutput := new(dynamodb.PutItemOutput)
 	return MockOutput, nil
 }
 
-// Put function in addDevice.go signature: input: (item map[string] *dynamodb.AttributeValue), output: (*dynamodb.PutItemOutput, error)
-func TestPut(t *testing.T) {
-	// Preparing a DynamoDB PuItemOutput data type as expected from a DB response.
-	MockInput := dynamodb.PutItemInput{}
-	MockInput.SetItem(
-		map[string]*dynamodb.AttributeValue{
-			"id":          &dynamodb.AttributeValue{S: aws.String("id1")},
-			"deviceModel": &dynamodb.AttributeValue{S: aws.String("testDeviceModel")},
-			"name":        &dynamodb.AttributeValue{S: aws.String("testName")},
-			"note":        &dynamodb.AttributeValue{S: aws.String("testNote")},
-			"serial":      &dynamodb.AttributeValue{S: aws.String("testSerial")},
-		},
-	)
+// Custom TransactWriteItems function for overriding the TransactWriteItems of devicemodels.Write for using in test scenarios.
+// The device is written like the mocked PutItem does. "deletingModel" is being deleted on the mocked DB.
+func (self *MockDynamoDB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	// The device model has to be checked and referenced in the same transaction.
+	items := input.TransactItems
+	if len(items) != 3 || items[1].ConditionCheck == nil || items[2].Put == nil {
+		return nil, awserr.New("ValidationException", "The device model is not guarded", nil)
+	}
+
+	put := items[0].Put
+	if _, err := self.PutItem(&dynamodb.PutItemInput{TableName: put.TableName, Item: put.Item, ConditionExpression: put.ConditionExpression}); err != nil {
+		return nil, Canceled("ConditionalCheckFailed", "None", "None")
+	}
+	if *items[1].ConditionCheck.Key["id"].S == "deletingModel" {
+		return nil, Canceled("None", "ConditionalCheckFailed", "None")
+	}
+	return new(dynamodb.TransactWriteItemsOutput), nil
+}
+
+// Returns the error of a cancelled transaction, with the reasons of its writes.
+func Canceled(codes ...string) error {
+	reasons := []*dynamodb.CancellationReason{}
+	for _, code := range codes {
+		reasons = append(reasons, &dynamodb.CancellationReason{Code: aws.String(code)})
+	}
+	return &dynamodb.TransactionCanceledException{CancellationReasons: reasons}
+}
 
+// Mocked idempotency table, which only checks whether the key is taken by a record which has not expired.
+func (self *MockDynamoDB) PutIdempotencyItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	key := *input.Item["key"].S
+	record, taken := self.IdempotencyRecords[key]
+	if taken && input.Item["statusCode"] == nil && !Expired(record, input.ExpressionAttributeValues[":now"]) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	self.IdempotencyRecords[key] = input.Item
+	return new(dynamodb.PutItemOutput), nil
+}
+
+// Tells whether a record of the mocked idempotency table has expired, like the condition of PutIdempotencyRecord.
+func Expired(record map[string]*dynamodb.AttributeValue, now *dynamodb.AttributeValue) bool {
+	if record["expiresAt"] == nil {
+		return false
+	}
+	expiresAt, _ := strconv.ParseInt(*record["expiresAt"].N, 10, 64)
+	at, _ := strconv.ParseInt(*now.N, 10, 64)
+	return expiresAt < at
+}
+
+// Custom GetItem function for overriding the GetItem of addDevice.go for using in test scenarios.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	if *input.TableName == "device_models_test" {
+		return MockDeviceModel(input)
+	}
+	return &dynamodb.GetItemOutput{Item: self.IdempotencyRecords[*input.Key["key"].S]}, nil
+}
+
+// Device models are looked up by their id. Only "testDeviceModel", "deletingModel" and "sensorModel" exist on the mocked DB.
+// Devices of "sensorModel" must have a number "range", and can have a "channel" of stable or beta.
+func MockDeviceModel(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	switch *input.Key["id"].S {
+	case "testDeviceModel", "deletingModel":
+		return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{"id": input.Key["id"]}}, nil
+	case "sensorModel":
+		item, _ := dynamodbattribute.MarshalMap(types.DeviceModel{ID: "sensorModel", Attributes: map[string]types.AttributeSchema{
+			"range":   {Type: types.NumberAttribute, Required: true},
+			"channel": {Type: types.EnumAttribute, Values: []string{"stable", "beta"}},
+		}})
+		return &dynamodb.GetItemOutput{Item: item}, nil
+	}
+	return new(dynamodb.GetItemOutput), nil
+}
+
+// Custom DeleteItem function for overriding the DeleteItem of addDevice.go for using in test scenarios.
+func (self *MockDynamoDB) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	delete(self.IdempotencyRecords, *input.Key["key"].S)
+	return new(dynamodb.DeleteItemOutput), nil
+}
+
+// Put function in addDevice.go signature: input: (device types.Device), output: (*dynamodb.TransactWriteItemsOutput, error)
+func TestPut(t *testing.T) {
 	// When  we have come up to putting item on DB, the Body data is standard and without any issues,
 	// Because we have validated the input body request in ValidateInputs functions beforehand in addDevice.go file
 	testCase := TestCase{
 		Name:          "** Testing JSON with proper fields **",
-		inputedItems:  MockInput.Item,
+		inputedDevice: types.Device{ID: "id1", DeviceModel: "/devicemodels/testDeviceModel", Name: "testName", Note: "testNote", Serial: "testSerial"},
 		ExpectedError: nil,
 	}
 
 	// Prepare AWS & DynamoDB session for mocking.
+	mock := &MockDynamoDB{}
 	test_aws := new(AmazonWebServices)
-	test_aws.DynamoDB = &MockDynamoDB{}
+	test_aws.DynamoDB = mock
 
-	_, err := test_aws.Put(testCase.inputedItems)
+	_, err := test_aws.Put(testCase.inputedDevice)
 
-	// Function here is %100 proof, so no error will happen.
-	if err != testCase.ExpectedError {
-		t.Errorf("%s \n \t<expected error: %t> <resulted error: %t>", testCase.ExpectedError, err)
+	// Function here is %100 proof, so no error will happen. Existing devices must never be overwritten though.
+	if err != testCase.ExpectedError || *mock.Input.ConditionExpression != "attribute_not_exists(id)" {
+		t.Errorf("%s \n \t<expected error: %v> <resulted error: %v>", testCase.Name, testCase.ExpectedError, err)
 	}
 } // End of TestPut function.
 
+// AddDevice function with mocked DB, for the responses which depend on DB's result.
+func TestAddDeviceConflict(t *testing.T) {
+	// Timestamps of the writes are known in advance.
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	os.Setenv("DEVICE_MODELS_TABLE_NAME", "device_models_test")
+	os.Setenv("ALLOW_CLIENT_IDS", "true")
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Attributes of the device model. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/sensorModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"attributes\":{\"range\":100,\"channel\":\"beta\"}}"},
+			ExpectedBody:       "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/sensorModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"attributes\":{\"channel\":\"beta\",\"range\":100},\"status\":\"provisioning\",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":1}",
+			ExpectedStatusCode: 201,
+		},
+
+		{
+			Name:               "** Testing: Missing required attribute. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/sensorModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"attributes\":{\"channel\":\"beta\"}}"},
+			ExpectedBody:       "Missing attribute: range",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Attribute of the wrong type. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/sensorModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"attributes\":{\"range\":\"100\"}}"},
+			ExpectedBody:       "Wrong attribute: range must be a number.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Value which the enum does not have. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/sensorModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"attributes\":{\"range\":100,\"channel\":\"nightly\"}}"},
+			ExpectedBody:       "Wrong attribute: channel must be one of stable, beta.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Attribute which the device model does not declare. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"attributes\":{\"range\":100}}"},
+			ExpectedBody:       "Unknown attribute: range is not an attribute of /devicemodels/testDeviceModel.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Unknown device model. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/typo\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "Unknown device model: /devicemodels/typo",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Device model which is being deleted. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/deletingModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "Unknown device model: /devicemodels/deletingModel",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Id which already exists. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"id_test\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "{\"code\":\"DeviceAlreadyExists\",\"message\":\"A device with id id_test already exists, use PUT or PATCH to change it.\"}",
+			ExpectedStatusCode: 409,
+		},
+
+		{
+			Name:               "** Testing: New id. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"version\":7}"},
+			ExpectedBody:       "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"status\":\"provisioning\",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":1}",
+			ExpectedStatusCode: 201,
+		},
+	}
+
+	// Replace the real AWS session with the mocked one, and put it back afterwards.
+	realAws := TestAws
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+	defer func() { TestAws = realAws }()
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := AddDevice(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestAddDeviceConflict function
+
 // ValidateDatabaseResult function in addDevice.go signature: input: (request events.APIGatewayProxyRequest), output: (Device, error)
 func TestAddDevice(t *testing.T) {
+	os.Setenv("ALLOW_CLIENT_IDS", "true")
+
 	testCases := []TestCase{
 		{
 			Name:               "** Testing: Empty body input. **",
@@ -82,13 +261,6 @@ func TestAddDevice(t *testing.T) {
 			ExpectedStatusCode: 400,
 		},
 
-		{
-			Name:               "** Testing: JSON with missing field - ID **",
-			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"\" , \"deviceModel\":\"testDeviceModel\" , \"name\":\"testName\" , \"note\":\"testNote\" , \"serial\":\"testSerial\" }"},
-			ExpectedBody:       "Missing field: ID",
-			ExpectedStatusCode: 400,
-		},
-
 		{
 			Name:               "** Testing: JSON with missing field - Device Model **",
 			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\" , \"deviceModel\":\"\" , \"name\":\"testName\" , \"note\":\"testNote\" , \"serial\":\"testSerial\" }"},
@@ -98,21 +270,21 @@ func TestAddDevice(t *testing.T) {
 
 		{
 			Name:               "** Testing: JSON with missing field - Name **",
-			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\" , \"deviceModel\":\"testDeviceModel\" , \"name\":\"\" , \"note\":\"testNote\" , \"serial\":\"testSerial\" }"},
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\" , \"deviceModel\":\"/devicemodels/testDeviceModel\" , \"name\":\"\" , \"note\":\"testNote\" , \"serial\":\"testSerial\" }"},
 			ExpectedBody:       "Missing field: Name",
 			ExpectedStatusCode: 400,
 		},
 
 		{
 			Name:               "** Testing: JSON with missing field - Note **",
-			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\" , \"deviceModel\":\"testDeviceModel\" , \"name\":\"testName\" , \"note\":\"\" , \"serial\":\"testSerial\" }"},
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\" , \"deviceModel\":\"/devicemodels/testDeviceModel\" , \"name\":\"testName\" , \"note\":\"\" , \"serial\":\"testSerial\" }"},
 			ExpectedBody:       "Missing field: Note",
 			ExpectedStatusCode: 400,
 		},
 
 		{
 			Name:               "** Testing: JSON with missing field - Serial **",
-			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\" , \"deviceModel\":\"testDeviceModel\" , \"name\":\"testName\" , \"note\":\"testNote\" , \"serial\":\"\" }"},
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\" , \"deviceModel\":\"/devicemodels/testDeviceModel\" , \"name\":\"testName\" , \"note\":\"testNote\" , \"serial\":\"\" }"},
 			ExpectedBody:       "Missing field: Serial",
 			ExpectedStatusCode: 400,
 		},
@@ -120,9 +292,9 @@ func TestAddDevice(t *testing.T) {
 		{ // In Testing environment, as we don't access AWS's OS environment variable and other real world parameters, can not reach to
 			// HTTP code 201 point in here, unless we prepare a mock server for it.
 			Name:         "** Testing: JSON with proper fields. **",
-			Request:      events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			Request:      events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
 			ExpectedBody: "Internal Server Error\nDatabase error.",
-			//ExpectedBody:        "{\"id\":\"1\",\"deviceModel\":\"testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}" ,
+			//ExpectedBody:        "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}" ,
 			ExpectedStatusCode: 500, //201
 		},
 	}
@@ -136,3 +308,136 @@ func TestAddDevice(t *testing.T) {
 	}
 
 } // end of TestAddDevice function
+
+// AddDevice function without client ids, the server generates them and tells where the device can be read from.
+func TestAddDeviceGeneratedId(t *testing.T) {
+	// Timestamps and random parts of the ids are known in advance.
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	ids.Entropy = func(random []byte) (int, error) {
+		for index := range random {
+			random[index] = 0xA5
+		}
+		return len(random), nil
+	}
+	os.Setenv("DEVICE_MODELS_TABLE_NAME", "device_models_test")
+	os.Setenv("DEVICE_ID_PREFIX", "dev_")
+	os.Setenv("ALLOW_CLIENT_IDS", "")
+	defer os.Setenv("DEVICE_ID_PREFIX", "")
+
+	realAws := TestAws
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+	defer func() { TestAws = realAws }()
+
+	// Clients can not choose ids, unless it's allowed.
+	response, _ := AddDevice(events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"})
+	if response.StatusCode != 400 || response.Body != "Wrong id: Ids are given by the server, please leave id out of the device." {
+		t.Errorf("** Testing: Id chosen by client. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+
+	// The request as API Gateway passes it on from the route of serverless.yml, with the stage in the path of the context.
+	request := events.APIGatewayProxyRequest{
+		Path:           "/devices",
+		RequestContext: events.APIGatewayProxyRequestContext{Path: "/dev/devices"},
+		Body:           "{\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}",
+	}
+	response, _ = AddDevice(request)
+	if response.StatusCode != 201 || !strings.HasPrefix(response.Body, "{\"id\":\"dev_01DXJ3BK48MPJTB9D5MPJTB9D5\",") || response.Headers["Location"] != "/dev/devices/dev_01DXJ3BK48MPJTB9D5MPJTB9D5" {
+		t.Errorf("** Testing: Generated id. ** \n \t<resulted error-code: %d> <resulted headers: %v> <resulted body: %s>", response.StatusCode, response.Headers, response.Body)
+	}
+
+	// Ids of the same millisecond still sort in the order they have been generated.
+	response, _ = AddDevice(request)
+	if response.Headers["Location"] != "/dev/devices/dev_01DXJ3BK48MPJTB9D5MPJTB9D6" {
+		t.Errorf("** Testing: Next generated id. ** \n \t<resulted headers: %v>", response.Headers)
+	}
+
+	// Ids chosen by clients are escaped in the URL.
+	os.Setenv("ALLOW_CLIENT_IDS", "true")
+	response, _ = AddDevice(events.APIGatewayProxyRequest{Path: "/devices", Body: "{\"id\":\"/devices/id1\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"})
+	if response.StatusCode != 201 || response.Headers["Location"] != "/devices/%2Fdevices%2Fid1" {
+		t.Errorf("** Testing: Id chosen by client. ** \n \t<resulted error-code: %d> <resulted headers: %v>", response.StatusCode, response.Headers)
+	}
+} // End of TestAddDeviceGeneratedId function
+
+// AddDevice function with Idempotency-Key header, retries must not write the device again.
+func TestAddDeviceIdempotency(t *testing.T) {
+	// Timestamps of the writes are known in advance.
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	os.Setenv("IDEMPOTENCY_TABLE_NAME", "idempotency_test")
+	os.Setenv("DEVICE_MODELS_TABLE_NAME", "device_models_test")
+	os.Setenv("ALLOW_CLIENT_IDS", "true")
+	body := "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"
+	created := "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"status\":\"provisioning\",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":1}"
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: First request with a key. **",
+			Request:            events.APIGatewayProxyRequest{Body: body, Headers: map[string]string{"Idempotency-Key": "key_test"}},
+			ExpectedBody:       created,
+			ExpectedStatusCode: 201,
+		},
+
+		{
+			Name:               "** Testing: Retry with the same key. **",
+			Request:            events.APIGatewayProxyRequest{Body: body, Headers: map[string]string{"idempotency-key": "key_test"}},
+			ExpectedBody:       created,
+			ExpectedStatusCode: 201,
+		},
+
+		{
+			Name:               "** Testing: Same key with another body. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{}", Headers: map[string]string{"Idempotency-Key": "key_test"}},
+			ExpectedBody:       "{\"code\":\"IdempotencyKeyReused\",\"message\":\"This Idempotency-Key has been used with another request body.\"}",
+			ExpectedStatusCode: 422,
+		},
+
+		{
+			Name:               "** Testing: Too long key. **",
+			Request:            events.APIGatewayProxyRequest{Body: body, Headers: map[string]string{"Idempotency-Key": strings.Repeat("k", 256)}},
+			ExpectedBody:       "Wrong format: Idempotency-Key must not be longer than 255 characters.",
+			ExpectedStatusCode: 400,
+		},
+	}
+
+	// Replace the real AWS session with the mocked one, and put it back afterwards.
+	mock := &MockDynamoDB{IdempotencyRecords: map[string]map[string]*dynamodb.AttributeValue{}}
+	realAws := TestAws
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+	defer func() { TestAws = realAws }()
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := AddDevice(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	if mock.Writes != 1 {
+		t.Errorf("** Testing: Device written once. ** \n \t<expected writes: %d> <resulted writes: %d>", 1, mock.Writes)
+	}
+
+	// A request in progress holds its key without a response.
+	mock.IdempotencyRecords["addDevice#busy_test"] = map[string]*dynamodb.AttributeValue{
+		"key":         &dynamodb.AttributeValue{S: aws.String("addDevice#busy_test")},
+		"requestHash": &dynamodb.AttributeValue{S: aws.String(HashRequest(events.APIGatewayProxyRequest{Body: body}))},
+		"expiresAt":   &dynamodb.AttributeValue{N: aws.String("1578020645")},
+	}
+	response, _ := AddDevice(events.APIGatewayProxyRequest{Body: body, Headers: map[string]string{"Idempotency-Key": "busy_test"}})
+	if response.StatusCode != 409 {
+		t.Errorf("** Testing: Request in progress. ** \n \t<expected error-code: %d> <resulted error-code: %d>", 409, response.StatusCode)
+	}
+
+	// An expired record which DynamoDB has not removed yet neither replays, nor blocks the key.
+	mock.IdempotencyRecords["addDevice#expired_test"] = map[string]*dynamodb.AttributeValue{
+		"key":         &dynamodb.AttributeValue{S: aws.String("addDevice#expired_test")},
+		"requestHash": &dynamodb.AttributeValue{S: aws.String(HashRequest(events.APIGatewayProxyRequest{Body: "{}"}))},
+		"statusCode":  &dynamodb.AttributeValue{N: aws.String("400")},
+		"body":        &dynamodb.AttributeValue{S: aws.String("expired_test")},
+		"expiresAt":   &dynamodb.AttributeValue{N: aws.String("1577934244")},
+	}
+	response, _ = AddDevice(events.APIGatewayProxyRequest{Body: strings.Replace(body, "\"1\"", "\"2\"", 1), Headers: map[string]string{"Idempotency-Key": "expired_test"}})
+	if response.StatusCode != 201 || mock.Writes != 2 {
+		t.Errorf("** Testing: Expired key. ** \n \t<expected error-code: %d> <resulted error-code: %d> <resulted body: %s>", 201, response.StatusCode, response.Body)
+	}
+} // End of TestAddDeviceIdempotency function
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"types"
+)
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's PutItem function inside.
+// Adding is create-only, an existing device model with the same id is never overwritten.
+func (self *AmazonWebServices) Put(item map[string]*dynamodb.AttributeValue) (*dynamodb.PutItemOutput, error) {
+	// Get table name from OS's environment
+	tableName := aws.String(os.Getenv("DEVICE_MODELS_TABLE_NAME"))
+	var input = &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           tableName,
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+	}
+	// Calling either PutItem function of interface, defined in addDeviceModel_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.PutItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func AddDeviceModel(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// First & foremost we have to validate user input.
+	NewModel, err := ValidateInputs(request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// Serialization/Encoding "NewModel" in "item" for using in DynamoDB functions.
+	item, _ := dynamodbattribute.MarshalMap(NewModel)
+
+	_, err = TestAws.Put(item)
+
+	// There is already a device model with this id. Return HTTP error code 409.
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		jsonResponse, _ := json.Marshal(types.Error{
+			Code:    "DeviceModelAlreadyExists",
+			Message: "A device model with id " + NewModel.ID + " already exists, use PUT to change it.",
+		})
+		return events.APIGatewayProxyResponse{
+			Body:       string(jsonResponse),
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			StatusCode: 409,
+		}, nil
+	}
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Serialization/Encoding "NewModel" to JSON.
+	jsonResponse, _ := json.Marshal(NewModel)
+	return events.APIGatewayProxyResponse{
+		Body: string(jsonResponse),
+		// Everything looks fine, return HTTP 201
+		StatusCode: 201,
+	}, nil
+} // End of AddDeviceModel function
+
+func ValidateInputs(request events.APIGatewayProxyRequest) (types.DeviceModel, error) {
+	// De-serialize "request.Body" which is in JSON format into "NewModel" in Go object.
+	NewModel, err := types.ParseDeviceModel(request.Body)
+	if err != nil {
+		return types.DeviceModel{}, err
+	}
+
+	if err = NewModel.Validate(); err != nil {
+		return types.DeviceModel{}, err
+	}
+
+	// Everything looks fine, return created NewModel in Go struct.
+	return NewModel, nil
+} // End of ValidateInputs function.
+
+func main() {
+	lambda.Start(AddDeviceModel)
+}
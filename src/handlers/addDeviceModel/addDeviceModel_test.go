@@ -0,0 +1,110 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked PutItem function has received.
+	Input *dynamodb.PutItemInput
+}
+
+// Custom PutItem function for overriding the PutItem of addDeviceModel.go for using in test scenarios.
+// "model_test" already exists on the mocked DB.
+func (self *MockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	self.Input = input
+	if *input.Item["id"].S == "model_test" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	return new(dynamodb.PutItemOutput), nil
+}
+
+// AddDeviceModel function in addDeviceModel.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestAddDeviceModel(t *testing.T) {
+	mock := &MockDynamoDB{}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty body input. **",
+			Request:            events.APIGatewayProxyRequest{Body: ""},
+			ExpectedBody:       "No inputs provided, please provide inputs in JSON format.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: JSON with missing field - Model Name **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"manufacturer\":\"testManufacturer\"}"},
+			ExpectedBody:       "Missing field: Model Name",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Id with a slash. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"a/b\",\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\"}"},
+			ExpectedBody:       "Wrong format: ID of a device model can not contain /.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Attribute of an unknown type. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\",\"attributes\":{\"range\":{\"type\":\"float\"}}}"},
+			ExpectedBody:       "Wrong format: Type of attribute range must be string, number, bool or enum.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Enum attribute without values. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\",\"attributes\":{\"channel\":{\"type\":\"enum\"}}}"},
+			ExpectedBody:       "Wrong format: Enum attribute channel must have values.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Id which already exists. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"model_test\",\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\"}"},
+			ExpectedBody:       "{\"code\":\"DeviceModelAlreadyExists\",\"message\":\"A device model with id model_test already exists, use PUT to change it.\"}",
+			ExpectedStatusCode: 409,
+		},
+
+		{
+			Name:               "** Testing: New device model with attributes. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"2\",\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\",\"attributes\":{\"channel\":{\"type\":\"enum\",\"values\":[\"stable\",\"beta\"]},\"range\":{\"type\":\"number\",\"required\":true}}}"},
+			ExpectedBody:       "{\"id\":\"2\",\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\",\"attributes\":{\"channel\":{\"type\":\"enum\",\"values\":[\"stable\",\"beta\"]},\"range\":{\"type\":\"number\",\"required\":true}}}",
+			ExpectedStatusCode: 201,
+		},
+
+		{
+			Name:               "** Testing: New device model. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"id\":\"1\",\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\",\"revision\":\"B\",\"specs\":{\"range\":\"0-100 C\"}}"},
+			ExpectedBody:       "{\"id\":\"1\",\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\",\"revision\":\"B\",\"specs\":{\"range\":\"0-100 C\"}}",
+			ExpectedStatusCode: 201,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := AddDeviceModel(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// Existing device models must never be overwritten.
+	if *mock.Input.ConditionExpression != "attribute_not_exists(id)" {
+		t.Errorf("** Testing: Create-only put. ** \n \t<resulted input: \n%s>", mock.Input.GoString())
+	}
+} // End of TestAddDeviceModel function
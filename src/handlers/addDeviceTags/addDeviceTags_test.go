@@ -0,0 +1,182 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked UpdateItem function has received.
+	UpdateInput *dynamodb.UpdateItemInput
+	// Number of times the mocked UpdateItem function has been called.
+	Updates int
+}
+
+// The stored device, tagged with site=berlin.
+func MockItem() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":          &dynamodb.AttributeValue{S: aws.String("id_test")},
+		"deviceModel": &dynamodb.AttributeValue{S: aws.String("deviceModel_test")},
+		"name":        &dynamodb.AttributeValue{S: aws.String("name_test")},
+		"note":        &dynamodb.AttributeValue{S: aws.String("note_test")},
+		"serial":      &dynamodb.AttributeValue{S: aws.String("serial_test")},
+		"tags":        &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{"site": {S: aws.String("berlin")}}},
+		"version":     &dynamodb.AttributeValue{N: aws.String("1")},
+	}
+}
+
+// Custom GetItem function for overriding the GetItem of addDeviceTags.go for using in test scenarios.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	mockOutput := new(dynamodb.GetItemOutput)
+	if id := *input.Key["id"].S; id == "id_test" || id == "busy_test" {
+		mockOutput.SetItem(MockItem())
+	}
+	return mockOutput, nil
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of addDeviceTags.go for using in test scenarios.
+// Mocking UpdateItem output to the stored item with the tags of the update.
+// "busy_test" is changed by someone else every time it is read, so its condition always fails.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	self.UpdateInput = input
+	self.Updates++
+	if *input.Key["id"].S == "busy_test" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	item := MockItem()
+	for _, value := range input.ExpressionAttributeValues {
+		if value.M != nil {
+			item["tags"] = value
+		}
+	}
+	item["version"] = &dynamodb.AttributeValue{N: aws.String("2")}
+	return &dynamodb.UpdateItemOutput{Attributes: item}, nil
+}
+
+// Update function in addDeviceTags.go signature: input: (id string, deviceTags map[string]string, by string, version int64), output: (*dynamodb.UpdateItemOutput, error)
+func TestUpdate(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	_, err := test_aws.Update("id_test", map[string]string{"site": "berlin", "env": "lab"}, "alice", 1)
+
+	// Keys and values of tags have to end up in attribute values only.
+	input := mock.UpdateInput
+	deviceTags := map[string]string{}
+	for _, value := range input.ExpressionAttributeValues {
+		if value.M != nil {
+			dynamodbattribute.UnmarshalMap(value.M, &deviceTags)
+		}
+	}
+	if err != nil || strings.Contains(*input.UpdateExpression, "site") || deviceTags["env"] != "lab" || !strings.Contains(*input.ConditionExpression, "=") || *input.ReturnValues != "ALL_NEW" {
+		t.Errorf("** Updating tags ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+} // End of TestUpdate function
+
+// AddDeviceTags function in addDeviceTags.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestAddDeviceTags(t *testing.T) {
+	TestCases := []TestCase{
+		{
+			Name:               "** Testing: Missing id. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"env\":\"lab\"}"},
+			ExpectedBody:       "Missing field : id",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Tags which are not strings. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"env\":1}"},
+			ExpectedBody:       "Wrong format: Inputs must be a JSON object of tags, i.e: {\"site\": \"berlin\"}.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: No tags. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{}"},
+			ExpectedBody:       "Wrong format: At least one tag must be provided.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Wrong key. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"Example.com/env\":\"lab\"}"},
+			ExpectedBody:       "Wrong format: prefix of tag key Example.com/env must be a lowercase DNS subdomain, i.e: example.com/site.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Wrong value. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"env\":\"lab one\"}"},
+			ExpectedBody:       "Wrong format: value of tag env must be at most 63 letters, digits, '-', '_' or '.', starting and ending with a letter or a digit.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Device not found. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "missing_test"}, Body: "{\"env\":\"lab\"}"},
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Stale If-Match. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"If-Match": "\"7\""}, Body: "{\"env\":\"lab\"}"},
+			ExpectedBody:       "{\"code\":\"PreconditionFailed\",\"message\":\"The device has been changed since it was read, read it again and retry.\",\"currentVersion\":1}",
+			ExpectedStatusCode: 412,
+		},
+
+		{
+			Name:               "** Testing: Adding a tag. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"If-Match": "\"1\""}, Body: "{\"env\":\"lab\"}"},
+			ExpectedBody:       "{\"id\":\"id_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\",\"tags\":{\"env\":\"lab\",\"site\":\"berlin\"},\"version\":2}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Changing the value of a tag. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"site\":\"paris\"}"},
+			ExpectedBody:       "{\"id\":\"id_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\",\"tags\":{\"site\":\"paris\"},\"version\":2}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Device is being changed by others. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "busy_test"}, Body: "{\"env\":\"lab\"}"},
+			ExpectedBody:       "Conflict: The device is being changed by others, please retry.",
+			ExpectedStatusCode: 409,
+		},
+	}
+
+	for _, test := range TestCases {
+		// Executing each test cases scenario.
+		TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+		response, _ := AddDeviceTags(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// Adding a tag the device already has does not write anything.
+	mock := &MockDynamoDB{}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+	response, _ := AddDeviceTags(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"site\":\"berlin\"}"})
+	if response.StatusCode != 200 || mock.Updates != 0 || response.Headers["ETag"] != "\"1\"" {
+		t.Errorf("** Testing: Adding an existing tag. ** \n \t<resulted error-code: %d> <resulted updates: %d> <resulted ETag: %s>", response.StatusCode, mock.Updates, response.Headers["ETag"])
+	}
+} // End of TestAddDeviceTags function
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"clock"
+	"cursor"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"os"
+	"time"
+	"types"
+)
+
+// How long one request keeps on backfilling, so it answers well before API Gateway gives up after 29 seconds.
+const MaxDuration = 20 * time.Second
+
+// Cursors of this endpoint can not be used on other endpoints.
+const CursorScope = "admin/devices:backfillSerialKeys"
+
+// Struct containing how far the backfill has come, for marshalling/unmarshalling.
+// Without a next cursor every device has its serial key.
+type BackfillResult struct {
+	Updated    int    `json:"updated"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's Scan function inside.
+// Only the devices which have a serial, but no serial key yet, are read.
+func (self *AmazonWebServices) Missing(startKey map[string]*dynamodb.AttributeValue) (*dynamodb.ScanOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	condition := expression.AttributeExists(expression.Name("serial")).
+		And(expression.AttributeNotExists(expression.Name("serialKey")))
+	expr, err := expression.NewBuilder().WithFilter(condition).
+		WithProjection(expression.NamesList(expression.Name("id"), expression.Name("serial"))).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.ScanInput{
+		TableName:                 tableName,
+		FilterExpression:          expr.Filter(),
+		ProjectionExpression:      expr.Projection(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+	// Continue right after the last item of the previous page.
+	if len(startKey) != 0 {
+		input.ExclusiveStartKey = startKey
+	}
+
+	// Calling either Scan function of interface, defined in backfillSerialKeys_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Scan(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// The serial key is derived from the serial, so the version of the device is left as it is.
+// A device which has been written meanwhile already has its key, and one which has been purged is not created again.
+func (self *AmazonWebServices) Update(device types.Device) (*dynamodb.UpdateItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	update := expression.Set(expression.Name("serialKey"), expression.Value(types.NormalizeSerial(device.Serial)))
+	condition := expression.Name("serial").Equal(expression.Value(device.Serial)).
+		And(expression.AttributeNotExists(expression.Name("serialKey")))
+
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(device.ID),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	// Calling either UpdateItem function of interface, defined in backfillSerialKeys_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.UpdateItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+// Devices written before the serial index get their serial key, page by page. When the time of one request is up,
+// the next cursor is returned and has to be sent back as cursor, until no next cursor is returned.
+func BackfillSerialKeys(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var startKey map[string]*dynamodb.AttributeValue
+	var err error
+	if token := request.QueryStringParameters["cursor"]; token != "" {
+		startKey, err = cursor.Decode(CursorScope, token)
+	}
+	// A missing cursor secret is a misconfiguration of the server, not a mistake of the client.
+	if err == cursor.ErrMissingSecret {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}, nil
+	}
+	// if the cursor is not valid, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	result := BackfillResult{}
+	started := clock.Now()
+	for {
+		page, err := TestAws.Missing(startKey)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+
+		devices := []types.Device{}
+		dynamodbattribute.UnmarshalListOfMaps(page.Items, &devices)
+		for _, device := range devices {
+			_, err := TestAws.Update(device)
+			// The device has been written or purged since it was read, nothing is left to do for it.
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+				continue
+			}
+			if err != nil {
+				return events.APIGatewayProxyResponse{
+					Body:       "Internal Server Error\nDatabase error.",
+					StatusCode: 500,
+				}, nil
+			}
+			result.Updated++
+		}
+
+		// No LastEvaluatedKey means the whole table has been read.
+		startKey = page.LastEvaluatedKey
+		if len(startKey) == 0 || clock.Now().Sub(started) >= MaxDuration {
+			break
+		}
+	}
+
+	result.NextCursor, err = cursor.Encode(CursorScope, startKey)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Serialization/Encoding result to JSON.
+	jsonResponse, _ := json.Marshal(result)
+	return events.APIGatewayProxyResponse{
+		Body:    string(jsonResponse),
+		Headers: map[string]string{"Content-Type": "application/json"},
+		// Everything looks fine, return HTTP 200
+		StatusCode: 200,
+	}, nil
+} // End of BackfillSerialKeys function
+
+func main() {
+	lambda.Start(BackfillSerialKeys)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"clock"
+	"cursor"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Values the mocked UpdateItem function has received, by the ids of the devices.
+	Values map[string]string
+}
+
+// Custom Scan function for overriding the Scan of backfillSerialKeys.go for using in test scenarios.
+// Mocking Scan output to a page with two devices, followed by a last page with one.
+func (self *MockDynamoDB) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	mockOutput := new(dynamodb.ScanOutput)
+
+	if input.ExclusiveStartKey == nil {
+		mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{
+			{"id": {S: aws.String("id_test")}, "serial": {S: aws.String("a02 0000102")}},
+			{"id": {S: aws.String("changed_test")}, "serial": {S: aws.String("old")}},
+		})
+		mockOutput.SetLastEvaluatedKey(map[string]*dynamodb.AttributeValue{"id": {S: aws.String("changed_test")}})
+		return mockOutput, nil
+	}
+	mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{
+		{"id": {S: aws.String("last_test")}, "serial": {S: aws.String("B1")}},
+	})
+	return mockOutput, nil
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of backfillSerialKeys.go for using in test scenarios.
+// "changed_test" has been written since it was read, so it fails the condition like on a real DB.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	id := *input.Key["id"].S
+	if id == "changed_test" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	for _, value := range input.ExpressionAttributeValues {
+		self.Values[id] += *value.S + ";"
+	}
+	return new(dynamodb.UpdateItemOutput), nil
+}
+
+// BackfillSerialKeys function in backfillSerialKeys.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestBackfillSerialKeys(t *testing.T) {
+	os.Setenv("CURSOR_SECRET", "secret_test")
+	nextCursor, _ := cursor.Encode(CursorScope, map[string]*dynamodb.AttributeValue{"id": {S: aws.String("changed_test")}})
+	otherCursor, _ := cursor.Encode("devices", map[string]*dynamodb.AttributeValue{"id": {S: aws.String("changed_test")}})
+
+	// Every reading of the clock is a minute later, so the time of a request is up after its first page.
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock.Now = func() time.Time {
+		at = at.Add(time.Minute)
+		return at
+	}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Cursor of another endpoint. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"cursor": otherCursor}},
+			ExpectedBody:       "Invalid cursor.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: First request, time is up after the first page. **",
+			Request:            events.APIGatewayProxyRequest{},
+			ExpectedBody:       "{\"updated\":1,\"nextCursor\":\"" + nextCursor + "\"}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Last request. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"cursor": nextCursor}},
+			ExpectedBody:       "{\"updated\":1}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	mock := &MockDynamoDB{Values: map[string]string{}}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := BackfillSerialKeys(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// Serial keys are written like the ones of new devices.
+	if !strings.Contains(mock.Values["id_test"], "a020000102;") || !strings.Contains(mock.Values["last_test"], "b1;") {
+		t.Errorf("** Testing: Serial keys. ** \n \t<resulted values: %v>", mock.Values)
+	}
+} // End of TestBackfillSerialKeys function
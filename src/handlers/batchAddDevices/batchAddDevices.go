@@ -0,0 +1,391 @@
+package main
+
+import (
+	"actor"
+	"devicemodels"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"ids"
+	"lifecycle"
+	"os"
+	"time"
+	"types"
+)
+
+// Most devices accepted in one batch, so a batch always finishes within the Lambda timeout.
+const MaxBatchSize = 500
+
+// Devices written in one transaction. Each device has two writes, and the checks of their device models are at most
+// as many, so a chunk always stays within the 100 writes DynamoDB accepts in one transaction.
+const ChunkSize = 25
+
+// How many times a chunk is written, when others are writing the same items or DynamoDB throttles the writes.
+const MaxAttempts = 5
+
+// Wait before the second write of a chunk, doubled for every further one.
+const BaseBackoff = 50 * time.Millisecond
+
+// Replaced in tests, so retries do not wait.
+var Sleep = time.Sleep
+
+// Struct containing the outcome of one entry of the batch, for marshalling/unmarshalling.
+type BatchResult struct {
+	Index  int           `json:"index"`
+	ID     string        `json:"id,omitempty"`
+	Status int           `json:"status"`
+	Device *types.Device `json:"device,omitempty"`
+	Error  *types.Error  `json:"error,omitempty"`
+}
+
+// Struct containing the outcome of every entry, in the order of the request.
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's TransactWriteItems function inside, for a chunk of devices.
+// Adding is create-only like Request 1, an existing item with the same id is never overwritten. The device models are
+// checked in the same transaction, so they can not be deleted while the devices are added. The writes are in the order
+// of Reasons: the devices, their references, then one check of each device model.
+func (self *AmazonWebServices) Put(devices []types.Device) (*dynamodb.TransactWriteItemsOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	writes := []*dynamodb.TransactWriteItem{}
+	references := []*dynamodb.TransactWriteItem{}
+	checks := map[string]*dynamodb.TransactWriteItem{}
+	for _, device := range devices {
+		// Serialization/Encoding "device" in "item" for using in DynamoDB functions.
+		item, err := dynamodbattribute.MarshalMap(device)
+		if err != nil {
+			return nil, err
+		}
+		writes = append(writes, &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				Item:                item,
+				TableName:           tableName,
+				ConditionExpression: aws.String("attribute_not_exists(id)"),
+			},
+		})
+
+		guard, err := devicemodels.Guard(device)
+		if err != nil {
+			return nil, err
+		}
+		references = append(references, guard[1])
+		// A transaction can not have two writes of the same item, so each device model is checked once.
+		checks[device.DeviceModel] = guard[0]
+	}
+	writes = append(writes, references...)
+	for _, reference := range Models(devices) {
+		writes = append(writes, checks[reference])
+	}
+
+	// Calling either TransactWriteItems function of interface, defined in batchAddDevices_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: writes})
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+// Every entry gets its own status, so one bad entry does not fail the whole batch.
+func BatchAddDevices(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// First & foremost we have to validate user input.
+	entries, err := ValidateInputs(request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	results := make([]BatchResult, len(entries))
+	devices := map[int]types.Device{}
+	seen := map[string]bool{}
+
+	for index, entry := range entries {
+		results[index].Index = index
+
+		NewDevice, err := ValidateDevice(entry)
+		if err != nil {
+			results[index].ID = NewDevice.ID
+			results[index].Status = 400
+			results[index].Error = &types.Error{Code: "InvalidDevice", Message: err.Error()}
+			continue
+		}
+		results[index].ID = NewDevice.ID
+
+		// Only the first entry of an id is written, later ones would fail as it already exists.
+		if seen[NewDevice.ID] {
+			results[index].Status = 409
+			results[index].Error = &types.Error{Code: "DuplicateId", Message: "The id " + NewDevice.ID + " is used by an earlier entry of this batch."}
+			continue
+		}
+		seen[NewDevice.ID] = true
+
+		// Every device starts with the first version, in the first state of its lifecycle.
+		NewDevice.Version = 1
+		NewDevice.Status = lifecycle.Initial
+		NewDevice = actor.New(NewDevice, actor.From(request))
+		// Kept for looking up the device by its serial.
+		NewDevice.SerialKey = types.NormalizeSerial(NewDevice.Serial)
+		devices[index] = NewDevice
+	}
+
+	// Devices of a batch mostly share a few models, each of them is only read once.
+	models := devicemodels.NewCache(TestAws.DynamoDB)
+	pending := []int{}
+	for index := range entries {
+		NewDevice, ok := devices[index]
+		if !ok {
+			continue
+		}
+
+		// The device model has to exist, so typos do not create phantom models, and attributes have to match its schema.
+		modelErr := models.Check(NewDevice)
+		if devicemodels.IsClientError(modelErr) {
+			results[index].Status = 400
+			results[index].Error = &types.Error{Code: "InvalidDevice", Message: modelErr.Error()}
+			continue
+		}
+		if modelErr != nil {
+			results[index].Status = 500
+			results[index].Error = &types.Error{Code: "InternalError", Message: "Internal Server Error\nDatabase error."}
+			continue
+		}
+		pending = append(pending, index)
+	}
+
+	// Devices are written in chunks of transactions, in the order of the request.
+	for start := 0; start < len(pending); start += ChunkSize {
+		end := start + ChunkSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		chunk := []types.Device{}
+		for _, index := range pending[start:end] {
+			chunk = append(chunk, devices[index])
+		}
+		for position, result := range AddChunk(chunk) {
+			index := pending[start+position]
+			result.Index = index
+			result.ID = results[index].ID
+			results[index] = result
+		}
+	}
+
+	return BatchResponseOf(results), nil
+} // End of BatchAddDevices function
+
+// Adds a chunk of devices, one transaction at a time. A transaction is canceled as a whole, so devices which fail their
+// own condition or the one of their device model are left out, and the others are written again right away. Conflicts
+// with other writes and throttling are retried with exponential backoff. Results are in the order of the chunk.
+func AddChunk(devices []types.Device) []BatchResult {
+	results := make([]BatchResult, len(devices))
+	pending := []int{}
+	for position := range devices {
+		pending = append(pending, position)
+	}
+
+	for attempt := 1; len(pending) != 0; {
+		chunk := []types.Device{}
+		for _, position := range pending {
+			chunk = append(chunk, devices[position])
+		}
+
+		_, err := TestAws.Put(chunk)
+		if err == nil {
+			for _, position := range pending {
+				results[position].Status = 201
+				results[position].Device = &devices[position]
+			}
+			return results
+		}
+
+		canceled, ok := err.(*dynamodb.TransactionCanceledException)
+		if !ok || len(canceled.CancellationReasons) != 2*len(chunk)+len(Models(chunk)) {
+			// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+			fmt.Println(fmt.Sprintf("Failed to add devices: %s", err.Error()))
+			break
+		}
+
+		reasons := Reasons(chunk, canceled.CancellationReasons)
+		retry := []int{}
+		retryable := false
+		for i, position := range pending {
+			switch reasons[i] {
+			// There is already a device with this id, even if it's deleted.
+			case "ConditionalCheckFailed":
+				results[position].Status = 409
+				results[position].Error = &types.Error{Code: "DeviceAlreadyExists", Message: "A device with id " + devices[position].ID + " already exists, use PUT or PATCH to change it."}
+			// The device model has been deleted since it was checked, or is being deleted.
+			case "UnknownDeviceModel":
+				results[position].Status = 400
+				results[position].Error = &types.Error{Code: "InvalidDevice", Message: devicemodels.UnknownError{Reference: devices[position].DeviceModel}.Error()}
+			case "None":
+				retry = append(retry, position)
+			default:
+				retry = append(retry, position)
+				retryable = true
+			}
+		}
+
+		// Nothing has been left out and nothing can be retried, so the chunk would fail the same way again.
+		if len(retry) == len(pending) && !retryable {
+			fmt.Println(fmt.Sprintf("Failed to add devices: %s", err.Error()))
+			break
+		}
+		pending = retry
+
+		if retryable {
+			if attempt == MaxAttempts {
+				break
+			}
+			Sleep(BaseBackoff << uint(attempt-1))
+			attempt++
+		}
+	}
+
+	// Devices which are still not written after all attempts.
+	for _, position := range pending {
+		if results[position].Status != 0 {
+			continue
+		}
+		results[position].Status = 500
+		results[position].Error = &types.Error{Code: "InternalError", Message: "Internal Server Error\nDatabase error."}
+	}
+	return results
+} // End of AddChunk function
+
+// The device models of the devices, each once in the order they are first used.
+func Models(devices []types.Device) []string {
+	models := []string{}
+	seen := map[string]bool{}
+	for _, device := range devices {
+		if !seen[device.DeviceModel] {
+			seen[device.DeviceModel] = true
+			models = append(models, device.DeviceModel)
+		}
+	}
+	return models
+} // End of Models function
+
+// Reasons tells for every device why the transaction of Put has been canceled. It's the reason of its own write, then
+// UnknownDeviceModel if the check of its device model has failed, then any other reason of its reference or model.
+func Reasons(devices []types.Device, reasons []*dynamodb.CancellationReason) []string {
+	checks := map[string]string{}
+	for position, reference := range Models(devices) {
+		checks[reference] = aws.StringValue(reasons[2*len(devices)+position].Code)
+	}
+
+	codes := make([]string, len(devices))
+	for position, device := range devices {
+		own := aws.StringValue(reasons[position].Code)
+		reference := aws.StringValue(reasons[len(devices)+position].Code)
+		check := checks[device.DeviceModel]
+		switch {
+		case own != "None":
+			codes[position] = own
+		case check == "ConditionalCheckFailed":
+			codes[position] = "UnknownDeviceModel"
+		case reference != "None":
+			codes[position] = reference
+		default:
+			codes[position] = check
+		}
+	}
+	return codes
+} // End of Reasons function
+
+// Returns the results with HTTP 207 Multi-Status, as each entry has its own status.
+func BatchResponseOf(results []BatchResult) events.APIGatewayProxyResponse {
+	// Serialization/Encoding results to JSON.
+	jsonResponse, _ := json.Marshal(BatchResponse{Results: results})
+	return events.APIGatewayProxyResponse{
+		Body:       string(jsonResponse),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		StatusCode: 207,
+	}
+} // End of BatchResponseOf function
+
+// Checks the request is a JSON array of acceptable size. The entries themselves are validated one by one.
+func ValidateInputs(request events.APIGatewayProxyRequest) ([]json.RawMessage, error) {
+	entries := []json.RawMessage{}
+
+	if len(request.Body) == 0 {
+		return nil, errors.New("No inputs provided, please provide inputs in JSON format.")
+	}
+
+	if err := json.Unmarshal([]byte(request.Body), &entries); err != nil {
+		return nil, errors.New("Wrong format: Inputs must be a valid JSON array of devices.")
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("No inputs provided, please provide at least one device.")
+	}
+
+	if len(entries) > MaxBatchSize {
+		return nil, fmt.Errorf("Too many devices: A batch can have at most %d devices.", MaxBatchSize)
+	}
+
+	// Everything looks fine, return the entries.
+	return entries, nil
+} // End of ValidateInputs function.
+
+// Validates one entry with the same rules as adding a single device.
+// The device is returned even when it's not valid, so its id can be reported.
+func ValidateDevice(entry json.RawMessage) (types.Device, error) {
+	NewDevice, err := types.ParseDevice(string(entry))
+	if err != nil {
+		return types.Device{}, err
+	}
+
+	// Ids are generated by the server, unless clients are allowed to choose them.
+	if NewDevice, err = ids.Assign(NewDevice); err != nil {
+		return NewDevice, err
+	}
+
+	if err = NewDevice.Validate(); err != nil {
+		return NewDevice, err
+	}
+
+	return NewDevice, nil
+} // End of ValidateDevice function.
+
+func main() {
+	lambda.Start(BatchAddDevices)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"clock"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"ids"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"types"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Inputs the mocked TransactWriteItems function has received.
+	Inputs []*dynamodb.TransactWriteItemsInput
+	// How many transactions are canceled by others writing the same devices, before one is written.
+	Conflicts int
+}
+
+// Custom TransactWriteItems function for overriding the TransactWriteItems of batchAddDevices.go for using in test scenarios.
+// "id_test" already exists on the mocked DB, as if someone has added it right before the write, and "deletedDeviceModel"
+// has been deleted after the devices have been checked. Like on a real DB, a transaction can write an item only once.
+func (self *MockDynamoDB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	self.Inputs = append(self.Inputs, input)
+	reasons := []*dynamodb.CancellationReason{}
+	keys := map[string]bool{}
+	canceled := false
+	for _, item := range input.TransactItems {
+		code, key := "None", ""
+		switch {
+		case item.ConditionCheck != nil:
+			key = "model " + *item.ConditionCheck.Key["id"].S
+			if *item.ConditionCheck.Key["id"].S == "deletedDeviceModel" {
+				code = "ConditionalCheckFailed"
+			}
+		case item.Put.ConditionExpression != nil:
+			key = "device " + *item.Put.Item["id"].S
+			if *item.Put.Item["id"].S == "id_test" {
+				code = "ConditionalCheckFailed"
+			}
+		default:
+			key = "reference " + *item.Put.Item["deviceModel"].S + " " + *item.Put.Item["id"].S
+		}
+
+		if keys[key] {
+			return nil, awserr.New("ValidationException", "Transaction request cannot include multiple operations on one item", nil)
+		}
+		keys[key] = true
+		canceled = canceled || code != "None"
+		reasons = append(reasons, &dynamodb.CancellationReason{Code: aws.String(code)})
+	}
+
+	if !canceled && self.Conflicts > 0 {
+		self.Conflicts--
+		reasons[0].Code = aws.String("TransactionConflict")
+		canceled = true
+	}
+	if canceled {
+		return nil, &dynamodb.TransactionCanceledException{CancellationReasons: reasons}
+	}
+	return new(dynamodb.TransactWriteItemsOutput), nil
+}
+
+// Custom GetItem function for overriding the GetItem of devicemodels.Check for using in test scenarios.
+// Only "testDeviceModel" and "deletedDeviceModel" exist on the mocked DB.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	if *input.Key["id"].S != "testDeviceModel" && *input.Key["id"].S != "deletedDeviceModel" {
+		return new(dynamodb.GetItemOutput), nil
+	}
+	return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{"id": input.Key["id"]}}, nil
+}
+
+func Entry(id string) string {
+	return fmt.Sprintf("{\"id\":\"%s\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}", id)
+}
+
+// Put function in batchAddDevices.go signature: input: (devices []types.Device), output: (*dynamodb.TransactWriteItemsOutput, error)
+func TestPut(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := &AmazonWebServices{DynamoDB: mock}
+
+	// Adding must never overwrite a device, even one added by others after the batch has been checked.
+	// Both devices share their device model, which is checked once, after the devices and their references.
+	_, err := test_aws.Put([]types.Device{{ID: "1", DeviceModel: "/devicemodels/testDeviceModel"}, {ID: "2", DeviceModel: "/devicemodels/testDeviceModel"}})
+	items := mock.Inputs[0].TransactItems
+	if err != nil || len(items) != 5 || *items[0].Put.ConditionExpression != "attribute_not_exists(id)" || *items[3].Put.Item["id"].S != "2" || items[4].ConditionCheck == nil {
+		t.Errorf("** Adding new items ** \n \t<resulted error: %v> <resulted input: \n%s>", err, mock.Inputs[0].GoString())
+	}
+} // End of TestPut function
+
+// BatchAddDevices function in batchAddDevices.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestBatchAddDevices(t *testing.T) {
+	// Timestamps of the writes are known in advance.
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	os.Setenv("ALLOW_CLIENT_IDS", "true")
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty body input. **",
+			Request:            events.APIGatewayProxyRequest{Body: ""},
+			ExpectedBody:       "No inputs provided, please provide inputs in JSON format.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Body is not an array. **",
+			Request:            events.APIGatewayProxyRequest{Body: Entry("1")},
+			ExpectedBody:       "Wrong format: Inputs must be a valid JSON array of devices.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Empty array. **",
+			Request:            events.APIGatewayProxyRequest{Body: "[]"},
+			ExpectedBody:       "No inputs provided, please provide at least one device.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Too many devices. **",
+			Request:            events.APIGatewayProxyRequest{Body: "[" + strings.Repeat("{},", MaxBatchSize) + "{}]"},
+			ExpectedBody:       "Too many devices: A batch can have at most 500 devices.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:    "** Testing: Mixed entries. **",
+			Request: events.APIGatewayProxyRequest{Body: "[" + Entry("1") + ",{\"id\":\"2\"}," + Entry("id_test") + "," + Entry("1") + ",7]"},
+			ExpectedBody: "{\"results\":[" +
+				"{\"index\":0,\"id\":\"1\",\"status\":201,\"device\":{\"id\":\"1\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"status\":\"provisioning\",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":1}}," +
+				"{\"index\":1,\"id\":\"2\",\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Missing field: Device Model\"}}," +
+				"{\"index\":2,\"id\":\"id_test\",\"status\":409,\"error\":{\"code\":\"DeviceAlreadyExists\",\"message\":\"A device with id id_test already exists, use PUT or PATCH to change it.\"}}," +
+				"{\"index\":3,\"id\":\"1\",\"status\":409,\"error\":{\"code\":\"DuplicateId\",\"message\":\"The id 1 is used by an earlier entry of this batch.\"}}," +
+				"{\"index\":4,\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Wrong format: Inputs must be a valid JSON.\"}}]}",
+			ExpectedStatusCode: 207,
+		},
+
+		{
+			Name:    "** Testing: Unknown device model. **",
+			Request: events.APIGatewayProxyRequest{Body: "[" + strings.Replace(Entry("1"), "testDeviceModel", "typo", 1) + "]"},
+			ExpectedBody: "{\"results\":[" +
+				"{\"index\":0,\"id\":\"1\",\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Unknown device model: /devicemodels/typo\"}}]}",
+			ExpectedStatusCode: 207,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := BatchAddDevices(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// Valid devices are written in chunks, in the order of the request.
+	mock := &MockDynamoDB{}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+	entries := []string{}
+	for i := 0; i < 60; i++ {
+		entries = append(entries, Entry(fmt.Sprint(i)))
+	}
+	BatchAddDevices(events.APIGatewayProxyRequest{Body: "[" + strings.Join(entries, ",") + "]"})
+	if len(mock.Inputs) != 3 || len(mock.Inputs[0].TransactItems) != 2*ChunkSize+1 || *mock.Inputs[2].TransactItems[9].Put.Item["id"].S != "59" {
+		t.Errorf("** Testing: Writing in chunks. ** \n \t<expected writes: 3> <resulted writes: %d>", len(mock.Inputs))
+	}
+} // End of TestBatchAddDevices function
+
+// BatchAddDevices function, with transactions canceled by others.
+func TestBatchAddDevicesCanceled(t *testing.T) {
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	os.Setenv("ALLOW_CLIENT_IDS", "true")
+	waits := []time.Duration{}
+	Sleep = func(wait time.Duration) { waits = append(waits, wait) }
+
+	// The device model is deleted after it has been checked, the other device is still added.
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+	deleted := strings.Replace(Entry("1"), "testDeviceModel", "deletedDeviceModel", 1)
+	response, _ := BatchAddDevices(events.APIGatewayProxyRequest{Body: "[" + deleted + "," + Entry("2") + "]"})
+	if !strings.Contains(response.Body, "{\"index\":0,\"id\":\"1\",\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Unknown device model: /devicemodels/deletedDeviceModel\"}}") || !strings.Contains(response.Body, "{\"index\":1,\"id\":\"2\",\"status\":201,") || len(waits) != 0 {
+		t.Errorf("** Testing: Deleted device model. ** \n \t<resulted body: %s> <resulted waits: %v>", response.Body, waits)
+	}
+
+	// Conflicts with other writes are retried after a while.
+	mock := &MockDynamoDB{Conflicts: 2}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+	response, _ = BatchAddDevices(events.APIGatewayProxyRequest{Body: "[" + Entry("1") + "," + Entry("2") + "]"})
+	if strings.Count(response.Body, "\"status\":201") != 2 || len(mock.Inputs) != 3 || len(waits) != 2 || waits[1] != 2*BaseBackoff {
+		t.Errorf("** Testing: Conflicting writes. ** \n \t<resulted body: %s> <resulted waits: %v>", response.Body, waits)
+	}
+
+	// Devices which others keep writing are given up after the last attempt.
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{Conflicts: MaxAttempts}}
+	response, _ = BatchAddDevices(events.APIGatewayProxyRequest{Body: "[" + Entry("1") + "]"})
+	expected := "{\"results\":[{\"index\":0,\"id\":\"1\",\"status\":500,\"error\":{\"code\":\"InternalError\",\"message\":\"Internal Server Error\\nDatabase error.\"}}]}"
+	if response.Body != expected {
+		t.Errorf("** Testing: Conflicting writes without end. ** \n \t<expected body: %s> <resulted body: %s>", expected, response.Body)
+	}
+} // End of TestBatchAddDevicesCanceled function
+
+// BatchAddDevices function without client ids, every device gets a generated id in the order of the batch.
+func TestBatchAddDevicesGeneratedIds(t *testing.T) {
+	// Timestamps and random parts of the ids are known in advance.
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	ids.Entropy = func(random []byte) (int, error) {
+		for index := range random {
+			random[index] = 0
+		}
+		return len(random), nil
+	}
+	os.Setenv("ALLOW_CLIENT_IDS", "")
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	withoutId := strings.Replace(Entry(""), "\"id\":\"\",", "", 1)
+	response, _ := BatchAddDevices(events.APIGatewayProxyRequest{Body: "[" + withoutId + "," + Entry("1") + "," + withoutId + "]"})
+	expected := "{\"results\":[" +
+		"{\"index\":0,\"id\":\"01DXJ3BK480000000000000000\",\"status\":201,\"device\":{\"id\":\"01DXJ3BK480000000000000000\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"status\":\"provisioning\",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":1}}," +
+		"{\"index\":1,\"id\":\"1\",\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Wrong id: Ids are given by the server, please leave id out of the device.\"}}," +
+		"{\"index\":2,\"id\":\"01DXJ3BK480000000000000001\",\"status\":201,\"device\":{\"id\":\"01DXJ3BK480000000000000001\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"status\":\"provisioning\",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":1}}]}"
+	if response.StatusCode != 207 || response.Body != expected {
+		t.Errorf("** Testing: Generated ids. ** \n \t<expected body: %s> <resulted body: %s>", expected, response.Body)
+	}
+} // End of TestBatchAddDevicesGeneratedIds function
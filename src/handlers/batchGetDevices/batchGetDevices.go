@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"time"
+	"types"
+)
+
+// Most ids accepted in one request, so the response stays within the Lambda payload limit.
+const MaxBatchSize = 500
+
+// Most keys DynamoDB accepts in one BatchGetItem request.
+const ReadChunkSize = 100
+
+// Number of times unprocessed keys are sent again, before the request fails.
+const MaxAttempts = 5
+
+// Waiting time before the first retry, doubled on every next one.
+const BaseBackoff = 50 * time.Millisecond
+
+// Replaced in tests, so retries do not slow them down.
+var Sleep = time.Sleep
+
+// Struct containing the request body, for marshalling/unmarshalling.
+type BatchGetRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// Struct containing the found devices, and the ids of the ones which were not found.
+type BatchGetResponse struct {
+	Devices []types.Device `json:"devices"`
+	Missing []string       `json:"missing"`
+}
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's BatchGetItem function inside, in chunks DynamoDB accepts.
+// Unprocessed keys are sent again with exponential backoff. Items are returned in no particular order.
+func (self *AmazonWebServices) BatchGet(ids []string) ([]map[string]*dynamodb.AttributeValue, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := os.Getenv("DEVICES_TABLE_NAME")
+	items := []map[string]*dynamodb.AttributeValue{}
+
+	for start := 0; start < len(ids); start += ReadChunkSize {
+		end := start + ReadChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		keys := []map[string]*dynamodb.AttributeValue{}
+		for _, id := range ids[start:end] {
+			keys = append(keys, map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}})
+		}
+		requestItems := map[string]*dynamodb.KeysAndAttributes{tableName: {Keys: keys}}
+
+		for attempt := 1; len(requestItems) != 0; attempt++ {
+			if attempt > MaxAttempts {
+				return nil, errors.New("Keys are still unprocessed after retrying.")
+			}
+			if attempt > 1 {
+				Sleep(BaseBackoff << uint(attempt-2))
+			}
+
+			// Calling either BatchGetItem function of interface, defined in batchGetDevices_test.go file, or api with the input we've provided.
+			result, err := self.DynamoDB.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: requestItems})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, result.Responses[tableName]...)
+			requestItems = result.UnprocessedKeys
+		}
+	}
+
+	return items, nil
+}
+
+// The handler function which will be first started from main function.
+func BatchGetDevices(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// First & foremost we have to validate user input.
+	ids, err := ValidateInputs(request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	items, err := TestAws.BatchGet(ids)
+
+	// Checking the result of the DynamoDB batch read.
+	return ValidateDatabaseResult(ids, items, err), nil
+} // End of BatchGetDevices function
+
+// Ids are taken from the JSON body of a POST, or from repeated "id" parameters of a GET.
+// Repeated ids are only looked up once, as DynamoDB rejects a batch with the same key twice.
+func ValidateInputs(request events.APIGatewayProxyRequest) ([]string, error) {
+	requested := request.MultiValueQueryStringParameters["id"]
+
+	if request.HTTPMethod != "GET" {
+		body := BatchGetRequest{}
+		if len(request.Body) == 0 {
+			return nil, errors.New("No inputs provided, please provide inputs in JSON format.")
+		}
+		if err := json.Unmarshal([]byte(request.Body), &body); err != nil {
+			return nil, errors.New("Wrong format: Inputs must be a valid JSON.")
+		}
+		requested = body.IDs
+	}
+
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, id := range requested {
+		if id == "" {
+			return nil, errors.New("Missing field: An id can not be empty.")
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.New("Missing field: ids")
+	}
+
+	if len(ids) > MaxBatchSize {
+		return nil, fmt.Errorf("Too many ids: At most %d devices can be read at once.", MaxBatchSize)
+	}
+
+	// Everything looks fine, return the ids to look up.
+	return ids, nil
+} // End of ValidateInputs function.
+
+// Founded devices are returned in the order of the requested ids. Like a single read, deleted devices are not found.
+func ValidateDatabaseResult(ids []string, items []map[string]*dynamodb.AttributeValue, err error) events.APIGatewayProxyResponse {
+	// If an internal error have occurred in the database, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}
+	}
+
+	found := map[string]types.Device{}
+	for _, item := range items {
+		device := types.Device{}
+		// Deserialization/Decoding "item" to Go struct.
+		dynamodbattribute.UnmarshalMap(item, &device)
+		if device.DeletedAt == "" {
+			found[device.ID] = device
+		}
+	}
+
+	response := BatchGetResponse{Devices: []types.Device{}, Missing: []string{}}
+	for _, id := range ids {
+		if device, ok := found[id]; ok {
+			response.Devices = append(response.Devices, device)
+		} else {
+			response.Missing = append(response.Missing, id)
+		}
+	}
+
+	// Serialization/Encoding response to JSON.
+	jsonResponse, _ := json.Marshal(response)
+
+	// Missing devices are part of the answer, so the status is 200 even if none was found.
+	return events.APIGatewayProxyResponse{
+		Body:       string(jsonResponse),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		StatusCode: 200,
+	}
+} // End of ValidateDatabaseResult function
+
+func main() {
+	lambda.Start(BatchGetDevices)
+}
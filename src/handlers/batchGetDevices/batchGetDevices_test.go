@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"testing"
+	"time"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Number of keys in each BatchGetItem call the mock has received.
+	ReadSizes []int
+	// Number of calls the mock leaves the last key of the request unprocessed.
+	Throttles int
+}
+
+// Custom BatchGetItem function for overriding the BatchGetItem of batchGetDevices.go for using in test scenarios.
+// Every id exists on the mocked DB, except "not_existed". "deleted_test" has been deleted.
+func (self *MockDynamoDB) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	output := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]*dynamodb.AttributeValue{},
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{},
+	}
+	for table, keys := range input.RequestItems {
+		self.ReadSizes = append(self.ReadSizes, len(keys.Keys))
+		processed := keys.Keys
+		if self.Throttles > 0 {
+			self.Throttles--
+			processed = keys.Keys[:len(keys.Keys)-1]
+			output.UnprocessedKeys[table] = &dynamodb.KeysAndAttributes{Keys: keys.Keys[len(keys.Keys)-1:]}
+		}
+		for _, key := range processed {
+			id := *key["id"].S
+			if id == "not_existed" {
+				continue
+			}
+			item := map[string]*dynamodb.AttributeValue{
+				"id":   &dynamodb.AttributeValue{S: aws.String(id)},
+				"name": &dynamodb.AttributeValue{S: aws.String("name_test")},
+			}
+			if id == "deleted_test" {
+				item["deletedAt"] = &dynamodb.AttributeValue{S: aws.String("2020-01-01T00:00:00Z")}
+			}
+			output.Responses[table] = append(output.Responses[table], item)
+		}
+	}
+	return output, nil
+}
+
+// BatchGet function in batchGetDevices.go signature: input: (ids []string), output: ([]map[string]*dynamodb.AttributeValue, error)
+func TestBatchGet(t *testing.T) {
+	Sleep = func(time.Duration) {}
+	ids := []string{}
+	for i := 0; i < 150; i++ {
+		ids = append(ids, fmt.Sprint(i))
+	}
+
+	mock := &MockDynamoDB{Throttles: 1}
+	test_aws := &AmazonWebServices{DynamoDB: mock}
+	items, err := test_aws.BatchGet(ids)
+	if err != nil || len(items) != 150 || fmt.Sprint(mock.ReadSizes) != "[100 1 50]" {
+		t.Errorf("** Reading in chunks with unprocessed keys ** \n \t<resulted items: %d> <resulted reads: %v>", len(items), mock.ReadSizes)
+	}
+
+	mock = &MockDynamoDB{Throttles: MaxAttempts}
+	test_aws = &AmazonWebServices{DynamoDB: mock}
+	_, err = test_aws.BatchGet(ids[:1])
+	if err == nil {
+		t.Errorf("** Giving up on unprocessed keys ** \n \t<resulted reads: %v>", mock.ReadSizes)
+	}
+} // End of TestBatchGet function
+
+// BatchGetDevices function in batchGetDevices.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestBatchGetDevices(t *testing.T) {
+	Sleep = func(time.Duration) {}
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty body input. **",
+			Request:            events.APIGatewayProxyRequest{HTTPMethod: "POST"},
+			ExpectedBody:       "No inputs provided, please provide inputs in JSON format.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: No ids. **",
+			Request:            events.APIGatewayProxyRequest{HTTPMethod: "POST", Body: "{\"ids\":[]}"},
+			ExpectedBody:       "Missing field: ids",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Empty id. **",
+			Request:            events.APIGatewayProxyRequest{HTTPMethod: "GET", MultiValueQueryStringParameters: map[string][]string{"id": {"id_test", ""}}},
+			ExpectedBody:       "Missing field: An id can not be empty.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Found, deleted and not existed devices. **",
+			Request:            events.APIGatewayProxyRequest{HTTPMethod: "POST", Body: "{\"ids\":[\"not_existed\",\"id_test\",\"deleted_test\",\"id_test\"]}"},
+			ExpectedBody:       "{\"devices\":[{\"id\":\"id_test\",\"deviceModel\":\"\",\"name\":\"name_test\",\"note\":\"\",\"serial\":\"\"}],\"missing\":[\"not_existed\",\"deleted_test\"]}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Repeated id parameters. **",
+			Request:            events.APIGatewayProxyRequest{HTTPMethod: "GET", MultiValueQueryStringParameters: map[string][]string{"id": {"id_test", "not_existed"}}},
+			ExpectedBody:       "{\"devices\":[{\"id\":\"id_test\",\"deviceModel\":\"\",\"name\":\"name_test\",\"note\":\"\",\"serial\":\"\"}],\"missing\":[\"not_existed\"]}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := BatchGetDevices(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestBatchGetDevices function
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"actor"
+	"clock"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"os"
+	"time"
+	"types"
+	"versioning"
+)
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// The device is only marked as deleted, so it can be restored until it gets purged.
+// If checkVersion is true, the device is only deleted when its stored version is expectedVersion.
+func (self *AmazonWebServices) SoftDelete(id string, deletedAt time.Time, by string, expectedVersion int64, checkVersion bool) (*dynamodb.UpdateItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	// The deletion is the last write of the device, at the same time.
+	at := clock.Format(deletedAt)
+	update := actor.StampAt(expression.Set(expression.Name("deletedAt"), expression.Value(at)), by, at)
+	// Devices which do not exist or are already deleted can not be deleted.
+	condition := expression.AttributeExists(expression.Name("id")).
+		And(expression.AttributeNotExists(expression.Name("deletedAt")))
+	if checkVersion {
+		condition = condition.And(versioning.Condition(expectedVersion))
+	}
+
+	expr, err := expression.NewBuilder().WithUpdate(versioning.Increment(update)).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	// Calling either UpdateItem function of interface, defined in deleteDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.UpdateItem(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside.
+func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	// Calling either GetItem function of interface, defined in deleteDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func DeleteDevice(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which user has sent through DELETE method.
+	id := request.PathParameters["id"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : id",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// Deleting can be made safe by sending the ETag of the device in If-Match header.
+	expectedVersion, checkVersion, err := versioning.IfMatch(request.Headers)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	_, err = TestAws.SoftDelete(id, clock.Now(), actor.From(request), expectedVersion, checkVersion)
+
+	// The condition fails if there is no device with this id, it's already deleted or has another version.
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ConditionFailedResponse(id, checkVersion), nil
+	}
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Everything looks fine, return HTTP 204 without any body.
+	return events.APIGatewayProxyResponse{
+		StatusCode: 204,
+	}, nil
+} // End of DeleteDevice function
+
+// Tells apart why the condition of the write has failed, by reading the device as it is now.
+func ConditionFailedResponse(id string, checkVersion bool) events.APIGatewayProxyResponse {
+	NotFound := events.APIGatewayProxyResponse{
+		Body:       "Desired device not found.",
+		StatusCode: 404,
+	}
+
+	// Without a version check, the device can only be missing or already deleted, return HTTP error code 404.
+	if !checkVersion {
+		return NotFound
+	}
+
+	result, err := TestAws.Get(id)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}
+	}
+
+	CurrentDevice := types.Device{}
+	dynamodbattribute.UnmarshalMap(result.Item, &CurrentDevice)
+
+	if len(result.Item) == 0 || CurrentDevice.DeletedAt != "" {
+		return NotFound
+	}
+
+	// The device exists but has another version than the client has expected, return HTTP error code 412.
+	return versioning.PreconditionFailed(CurrentDevice.Version)
+} // End of ConditionFailedResponse function
+
+func main() {
+	lambda.Start(DeleteDevice)
+}
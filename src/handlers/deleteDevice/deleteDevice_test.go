@@ -0,0 +1,144 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked UpdateItem function has received.
+	Input *dynamodb.UpdateItemInput
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of deleteDevice.go for using in test scenarios.
+// Only "id_test" exists on the mocked DB and is not deleted yet, other ids and versions fail the condition like on a real DB.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	self.Input = input
+	if *input.Key["id"].S != "id_test" || OutdatedVersion(input.ExpressionAttributeValues) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	return new(dynamodb.UpdateItemOutput), nil
+}
+
+// Custom GetItem function for overriding the GetItem of deleteDevice.go for using in test scenarios.
+// "id_test" is a device and "deleted_test" a deleted device on the mocked DB, both with version 1.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	MockOutput := new(dynamodb.GetItemOutput)
+	switch *input.Key["id"].S {
+	case "id_test":
+		MockOutput.SetItem(map[string]*dynamodb.AttributeValue{
+			"id":      &dynamodb.AttributeValue{S: aws.String("id_test")},
+			"version": &dynamodb.AttributeValue{N: aws.String("1")},
+		})
+	case "deleted_test":
+		MockOutput.SetItem(map[string]*dynamodb.AttributeValue{
+			"id":        &dynamodb.AttributeValue{S: aws.String("deleted_test")},
+			"deletedAt": &dynamodb.AttributeValue{S: aws.String("2020-01-02T03:04:05Z")},
+			"version":   &dynamodb.AttributeValue{N: aws.String("1")},
+		})
+	}
+	return MockOutput, nil
+}
+
+// Besides the expected version, the only number in the input may be the increment of the version.
+func OutdatedVersion(values map[string]*dynamodb.AttributeValue) bool {
+	for _, value := range values {
+		if value.N != nil && *value.N != "1" {
+			return true
+		}
+	}
+	return false
+}
+
+// SoftDelete function in deleteDevice.go signature: input: (id string, deletedAt time.Time, by string, expectedVersion int64, checkVersion bool), output: (*dynamodb.UpdateItemOutput, error)
+func TestSoftDelete(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	deletedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	_, err := test_aws.SoftDelete("id_test", deletedAt, "alice", 1, true)
+
+	// The deletion is the last write of the device, so it's stamped with the same time.
+	deletedAtValues := 0
+	for _, value := range mock.Input.ExpressionAttributeValues {
+		if value.S != nil && *value.S == "2020-01-02T03:04:05Z" {
+			deletedAtValues++
+		}
+	}
+	if err != nil || deletedAtValues != 2 || !strings.Contains(*mock.Input.UpdateExpression, "ADD") {
+		t.Errorf("** Soft deleting existed item ** \n \t<resulted input: \n%s>", mock.Input.GoString())
+	}
+} // End of TestSoftDelete function
+
+// DeleteDevice function in deleteDevice.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestDeleteDevice(t *testing.T) {
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty id input. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": ""}},
+			ExpectedBody:       "Missing field : id",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Device does not exist or is already deleted. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "not_existed"}},
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Invalid If-Match. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"If-Match": "1"}},
+			ExpectedBody:       "Wrong format: If-Match must be a single ETag, i.e: \"3\".",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Outdated If-Match. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"If-Match": "\"5\""}},
+			ExpectedBody:       "{\"code\":\"PreconditionFailed\",\"message\":\"The device has been changed since it was read, read it again and retry.\",\"currentVersion\":1}",
+			ExpectedStatusCode: 412,
+		},
+
+		{
+			Name:               "** Testing: If-Match of a deleted device. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "deleted_test"}, Headers: map[string]string{"If-Match": "\"1\""}},
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Deleting existed device. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}},
+			ExpectedBody:       "",
+			ExpectedStatusCode: 204,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := DeleteDevice(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestDeleteDevice function
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"clock"
+	"devicemodels"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"os"
+	"strconv"
+	"time"
+	"types"
+)
+
+// Global secondary index of the devices table on the device model.
+const DeviceModelIndex = "deviceModel-name-index"
+
+// How long a deletion keeps devices from getting the device model, see devicemodels.DeletingUntil.
+// API Gateway waits 29 seconds at most, so a deletion has either finished or failed by then.
+const DeletionLease = time.Minute
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// From now on until the given time, no device can get the device model, see devicemodels.Guard.
+func (self *AmazonWebServices) MarkDeleting(id string, until int64) (*dynamodb.UpdateItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICE_MODELS_TABLE_NAME"))
+
+	update := expression.Set(expression.Name(devicemodels.DeletingUntil), expression.Value(until))
+	condition := expression.AttributeExists(expression.Name("id"))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	// Calling either UpdateItem function of interface, defined in deleteDeviceModel_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.UpdateItem(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// Devices can get the device model again. A mark of a later deletion is left as it is.
+func (self *AmazonWebServices) Unmark(id string, until int64) (*dynamodb.UpdateItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICE_MODELS_TABLE_NAME"))
+
+	update := expression.Remove(expression.Name(devicemodels.DeletingUntil))
+	condition := expression.Name(devicemodels.DeletingUntil).Equal(expression.Value(until))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	// Calling either UpdateItem function of interface, defined in deleteDeviceModel_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.UpdateItem(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's Query function inside, on the references table.
+// Every device which has got the device model through devicemodels.Guard is there, read consistently.
+func (self *AmazonWebServices) References(id string, startKey map[string]*dynamodb.AttributeValue) (*dynamodb.QueryOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("MODEL_REFERENCES_TABLE_NAME"))
+
+	keyCondition := expression.Key("deviceModel").Equal(expression.Value(devicemodels.Reference(id)))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.QueryInput{
+		TableName:                 tableName,
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		// A device which has got the model right before the deletion has to be found.
+		ConsistentRead: aws.Bool(true),
+	}
+	// Continue right after the last reference of the previous page.
+	if len(startKey) != 0 {
+		input.ExclusiveStartKey = startKey
+	}
+
+	// Calling either Query function of interface, defined in deleteDeviceModel_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Query(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside.
+// Only the device model of the device is read, as it is right now. Purged devices have none.
+func (self *AmazonWebServices) DeviceModelOf(deviceId string) (string, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(deviceId),
+			},
+		},
+		ProjectionExpression: aws.String("deviceModel"),
+		ConsistentRead:       aws.Bool(true),
+	}
+
+	// Calling either GetItem function of interface, defined in deleteDeviceModel_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	if err != nil {
+		return "", err
+	}
+
+	device := types.Device{}
+	err = dynamodbattribute.UnmarshalMap(result.Item, &device)
+	return device.DeviceModel, err
+}
+
+// Preparing DynamoDB Session and Calling DB's DeleteItem function inside, on the references table.
+// Only for devices which do not have the device model anymore.
+func (self *AmazonWebServices) Forget(id string, deviceId string) (*dynamodb.DeleteItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("MODEL_REFERENCES_TABLE_NAME"))
+
+	var input = &dynamodb.DeleteItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"deviceModel": {
+				S: aws.String(devicemodels.Reference(id)),
+			},
+			"id": {
+				S: aws.String(deviceId),
+			},
+		},
+	}
+
+	// Calling either DeleteItem function of interface, defined in deleteDeviceModel_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.DeleteItem(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's Query function inside, on the device model index of the devices table.
+// Deleted devices are counted as well, as they can still be restored. The index is updated after the writes of devices,
+// so it's only asked for devices which have been written before there were references.
+func (self *AmazonWebServices) IsIndexed(id string) (bool, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	keyCondition := expression.Key("deviceModel").Equal(expression.Value(devicemodels.Reference(id)))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		return false, err
+	}
+
+	var input = &dynamodb.QueryInput{
+		TableName:                 tableName,
+		IndexName:                 aws.String(DeviceModelIndex),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		// A single device is enough to know the model is in use.
+		Limit: aws.Int64(1),
+	}
+
+	// Calling either Query function of interface, defined in deleteDeviceModel_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Query(input)
+	if err != nil {
+		return false, err
+	}
+	return len(result.Items) != 0, nil
+}
+
+// Preparing DynamoDB Session and Calling DB's DeleteItem function inside.
+// The device model is only deleted while it's still marked by this deletion, so no device can have got it meanwhile.
+func (self *AmazonWebServices) Delete(id string, until int64) (*dynamodb.DeleteItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICE_MODELS_TABLE_NAME"))
+
+	var input = &dynamodb.DeleteItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		ConditionExpression:      aws.String("#deletingUntil = :until"),
+		ExpressionAttributeNames: map[string]*string{"#deletingUntil": aws.String(devicemodels.DeletingUntil)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":until": {
+				N: aws.String(strconv.FormatInt(until, 10)),
+			},
+		},
+	}
+
+	// Calling either DeleteItem function of interface, defined in deleteDeviceModel_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.DeleteItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+// Device models which are still used by devices can not be deleted.
+func DeleteDeviceModel(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which user has sent through DELETE method.
+	id := request.PathParameters["modelId"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : modelId",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// From now on no device can get the device model, so the devices found below are all of them.
+	until := clock.Now().Add(DeletionLease).Unix()
+	_, err := TestAws.MarkDeleting(id, until)
+
+	// The device model does not exist, return HTTP error code 404.
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return events.APIGatewayProxyResponse{
+			Body:       "Desired device model not found.",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	referenced, err := IsReferenced(id)
+	// The device model stays, devices can get it again. If the mark can not be removed, it runs out by itself.
+	if err != nil || referenced {
+		TestAws.Unmark(id, until)
+	}
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Deleting the model would leave its devices with a reference to nothing, return HTTP error code 409.
+	if referenced {
+		jsonResponse, _ := json.Marshal(types.Error{
+			Code:    "DeviceModelInUse",
+			Message: "The device model " + id + " is still used by devices, change or purge them first.",
+		})
+		return events.APIGatewayProxyResponse{
+			Body:       string(jsonResponse),
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			StatusCode: 409,
+		}, nil
+	}
+
+	_, err = TestAws.Delete(id, until)
+
+	// The device model has been replaced or deleted by others meanwhile, which has removed the mark. Return HTTP error code 409.
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return events.APIGatewayProxyResponse{
+			Body:       "Conflict: The device model is being changed by others, please retry.",
+			StatusCode: 409,
+		}, nil
+	}
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Everything looks fine, return HTTP 204 without a body.
+	return events.APIGatewayProxyResponse{
+		StatusCode: 204,
+	}, nil
+} // End of DeleteDeviceModel function
+
+// Tells whether any device has the device model. Devices which have got it through devicemodels.Guard are in its references,
+// a reference is forgotten when its device has another model by now or has been purged. Devices written before there were
+// references are only found on the device model index.
+func IsReferenced(id string) (bool, error) {
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		page, err := TestAws.References(id, startKey)
+		if err != nil {
+			return false, err
+		}
+
+		references := []types.Device{}
+		dynamodbattribute.UnmarshalListOfMaps(page.Items, &references)
+		for _, reference := range references {
+			model, err := TestAws.DeviceModelOf(reference.ID)
+			if err != nil {
+				return false, err
+			}
+			if model == devicemodels.Reference(id) {
+				return true, nil
+			}
+			if _, err = TestAws.Forget(id, reference.ID); err != nil {
+				return false, err
+			}
+		}
+
+		// No LastEvaluatedKey means every reference has been read.
+		startKey = page.LastEvaluatedKey
+		if len(startKey) == 0 {
+			break
+		}
+	}
+
+	return TestAws.IsIndexed(id)
+} // End of IsReferenced function
+
+func main() {
+	lambda.Start(DeleteDeviceModel)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"clock"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input of the device model index the mocked Query function has received.
+	QueryInput *dynamodb.QueryInput
+	// Device models which are marked as being deleted on the mocked DB.
+	Marked map[string]bool
+	// References the mocked DeleteItem function has removed.
+	Forgotten []string
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of deleteDeviceModel.go for using in test scenarios.
+// "not_existed" does not exist on the mocked DB, every other device model does.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	id := *input.Key["id"].S
+	if id == "not_existed" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	self.Marked[id] = strings.HasPrefix(*input.UpdateExpression, "SET")
+	return new(dynamodb.UpdateItemOutput), nil
+}
+
+// Custom Query function for overriding the Query of deleteDeviceModel.go for using in test scenarios.
+// "used_test" is in the references of "id_test", which still has it, and "moved_test" in the ones of "moved_device",
+// which has another model by now. "legacy_test" is only on the device model index.
+func (self *MockDynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	mockOutput := new(dynamodb.QueryOutput)
+	reference := ""
+	for _, value := range input.ExpressionAttributeValues {
+		reference = *value.S
+	}
+
+	if input.IndexName != nil {
+		self.QueryInput = input
+		if reference == "/devicemodels/legacy_test" {
+			mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{{"id": {S: aws.String("legacy_device")}}})
+		}
+		return mockOutput, nil
+	}
+
+	// References have to be read consistently, so a device written right before the mark is found.
+	if input.ConsistentRead == nil || !*input.ConsistentRead {
+		return nil, awserr.New("ValidationException", "References are read eventually consistent", nil)
+	}
+	switch reference {
+	case "/devicemodels/used_test":
+		mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{{"deviceModel": {S: aws.String(reference)}, "id": {S: aws.String("id_test")}}})
+	case "/devicemodels/moved_test":
+		mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{{"deviceModel": {S: aws.String(reference)}, "id": {S: aws.String("moved_device")}}})
+	}
+	return mockOutput, nil
+}
+
+// Custom GetItem function for overriding the GetItem of deleteDeviceModel.go for using in test scenarios.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	models := map[string]string{"id_test": "/devicemodels/used_test", "moved_device": "/devicemodels/other_test"}
+	return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+		"deviceModel": {S: aws.String(models[*input.Key["id"].S])},
+	}}, nil
+}
+
+// Custom DeleteItem function for overriding the DeleteItem of deleteDeviceModel.go for using in test scenarios.
+// Device models are only deleted while they are marked, "replaced_test" has been replaced since it was marked.
+func (self *MockDynamoDB) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	if *input.TableName == "references_test" {
+		self.Forgotten = append(self.Forgotten, *input.Key["id"].S)
+		return new(dynamodb.DeleteItemOutput), nil
+	}
+	if id := *input.Key["id"].S; !self.Marked[id] || id == "replaced_test" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	return new(dynamodb.DeleteItemOutput), nil
+}
+
+// DeleteDeviceModel function in deleteDeviceModel.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestDeleteDeviceModel(t *testing.T) {
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	os.Setenv("MODEL_REFERENCES_TABLE_NAME", "references_test")
+	mock := &MockDynamoDB{Marked: map[string]bool{}}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty id input. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": ""}},
+			ExpectedBody:       "Missing field : modelId",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Device model used by devices. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "used_test"}},
+			ExpectedBody:       "{\"code\":\"DeviceModelInUse\",\"message\":\"The device model used_test is still used by devices, change or purge them first.\"}",
+			ExpectedStatusCode: 409,
+		},
+
+		{
+			Name:               "** Testing: Device model used by devices written before references. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "legacy_test"}},
+			ExpectedBody:       "{\"code\":\"DeviceModelInUse\",\"message\":\"The device model legacy_test is still used by devices, change or purge them first.\"}",
+			ExpectedStatusCode: 409,
+		},
+
+		{
+			Name:               "** Testing: Device model does not exist. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "not_existed"}},
+			ExpectedBody:       "Desired device model not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Device model replaced during the deletion. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "replaced_test"}},
+			ExpectedBody:       "Conflict: The device model is being changed by others, please retry.",
+			ExpectedStatusCode: 409,
+		},
+
+		{
+			Name:               "** Testing: Device model whose device has moved to another one. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "moved_test"}},
+			ExpectedBody:       "",
+			ExpectedStatusCode: 204,
+		},
+
+		{
+			Name:               "** Testing: Unused device model. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "model_test"}},
+			ExpectedBody:       "",
+			ExpectedStatusCode: 204,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := DeleteDeviceModel(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// Models which are still used can get devices again, the reference of a moved device is forgotten.
+	if mock.Marked["used_test"] || mock.Marked["legacy_test"] || len(mock.Forgotten) != 1 || mock.Forgotten[0] != "moved_device" {
+		t.Errorf("** Testing: Marks and references. ** \n \t<resulted marks: %v> <resulted forgotten: %v>", mock.Marked, mock.Forgotten)
+	}
+
+	// Deleted devices still count, so no filter may skip them.
+	if *mock.QueryInput.IndexName != DeviceModelIndex || mock.QueryInput.FilterExpression != nil {
+		t.Errorf("** Testing: Looking for devices of the model. ** \n \t<resulted input: \n%s>", mock.QueryInput.GoString())
+	}
+} // End of TestDeleteDeviceModel function
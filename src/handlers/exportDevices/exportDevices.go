@@ -0,0 +1,171 @@
+package main
+
+import (
+	"actor"
+	"clock"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"types"
+)
+
+// How long exports are kept, like the exports bucket keeps their files.
+const Retention = 7 * 24 * time.Hour
+
+// Formats an export can be written in.
+const (
+	CSVFormat    = "csv"
+	NDJSONFormat = "ndjson"
+)
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's PutItem function inside.
+// Writing the export to its table starts runExport through the stream of the table.
+func (self *AmazonWebServices) Put(export types.Export) (*dynamodb.PutItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("EXPORTS_TABLE_NAME"))
+
+	// Serialization/Encoding "export" in "item" for using in DynamoDB functions.
+	item, err := dynamodbattribute.MarshalMap(export)
+	if err != nil {
+		return nil, err
+	}
+
+	// Request ids never repeat, the condition only makes sure an export is never overwritten.
+	expr, err := expression.NewBuilder().WithCondition(expression.AttributeNotExists(expression.Name("id"))).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.PutItemInput{
+		Item:                     item,
+		TableName:                tableName,
+		ConditionExpression:      expr.Condition(),
+		ExpressionAttributeNames: expr.Names(),
+	}
+
+	// Calling either PutItem function of interface, defined in exportDevices_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.PutItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+// Exporting a big table takes longer than API Gateway waits, so the export is only started here and runs in runExport.
+// The response points to the export, which clients read with getExport until it has succeeded or failed.
+func ExportDevices(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// First & foremost we have to validate user input.
+	format, err := ValidateInputs(request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// API Gateway gives every request an id, exports are named by it.
+	now := clock.Now()
+	id := request.RequestContext.RequestID
+	export := types.Export{
+		ID:        id,
+		Format:    format,
+		Status:    types.ExportPending,
+		CreatedAt: clock.Format(now),
+		CreatedBy: actor.From(request),
+		Key:       ExportKey(format, id, now),
+		ExpiresAt: now.Add(Retention).Unix(),
+	}
+
+	_, err = TestAws.Put(export)
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to start export %s: %s", id, err.Error()))
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Serialization/Encoding export to JSON.
+	jsonResponse, _ := json.Marshal(export)
+
+	// Return HTTP 202 Accepted, with the export to follow in Location.
+	return events.APIGatewayProxyResponse{
+		Body:       string(jsonResponse),
+		Headers:    map[string]string{"Content-Type": "application/json", "Location": Location(request, id)},
+		StatusCode: 202,
+	}, nil
+} // End of ExportDevices function
+
+// Location is the path of the export, under the path the request has been sent to.
+func Location(request events.APIGatewayProxyRequest, id string) string {
+	path := request.RequestContext.Path
+	if path == "" {
+		path = request.Path
+	}
+	return strings.TrimSuffix(path, "/") + "/" + url.PathEscape(id)
+}
+
+func ValidateInputs(request events.APIGatewayProxyRequest) (string, error) {
+	format := CSVFormat
+	if value, ok := request.QueryStringParameters["format"]; ok {
+		if value != CSVFormat && value != NDJSONFormat {
+			return "", errors.New("Wrong format: format must be " + CSVFormat + " or " + NDJSONFormat + ".")
+		}
+		format = value
+	}
+
+	// Everything looks fine, return the format of the export.
+	return format, nil
+} // End of ValidateInputs function.
+
+// Exports are named by the time they were made, and the request which made them so two of them never collide.
+func ExportKey(format string, requestId string, now time.Time) string {
+	name := "devices-" + now.UTC().Format("20060102T150405Z")
+	if requestId != "" {
+		name += "-" + requestId
+	}
+	return "exports/" + name + "." + format
+} // End of ExportKey function
+
+func main() {
+	lambda.Start(ExportDevices)
+}
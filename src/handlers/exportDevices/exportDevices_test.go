@@ -0,0 +1,69 @@
+package main
+
+import (
+	"clock"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"testing"
+	"time"
+	"types"
+)
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Export the mocked PutItem function has received last.
+	Export types.Export
+}
+
+// Custom PutItem function for overriding the PutItem of exportDevices.go for using in test scenarios.
+// Exports of "failing_test" can not be written.
+func (self *MockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	dynamodbattribute.UnmarshalMap(input.Item, &self.Export)
+	if self.Export.ID == "failing_test" || input.ConditionExpression == nil {
+		return nil, errors.New("Throttled")
+	}
+	return new(dynamodb.PutItemOutput), nil
+}
+
+// ExportDevices function in exportDevices.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestExportDevices(t *testing.T) {
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	mock := &MockDynamoDB{}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+
+	response, _ := ExportDevices(events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"format": "xml"}})
+	if response.StatusCode != 400 || response.Body != "Wrong format: format must be csv or ndjson." {
+		t.Errorf("** Testing: Unknown format. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+
+	request := events.APIGatewayProxyRequest{Path: "/devices/export", QueryStringParameters: map[string]string{"format": "ndjson"}}
+	request.RequestContext.RequestID = "request_test"
+	response, _ = ExportDevices(request)
+	expected := "{\"id\":\"request_test\",\"format\":\"ndjson\",\"status\":\"pending\",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\"}"
+	if response.StatusCode != 202 || response.Body != expected || response.Headers["Location"] != "/devices/export/request_test" {
+		t.Errorf("** Testing: Starting an export. ** \n \t<expected body: %s> \n \t<resulted error-code: %d> <resulted headers: %v> <resulted body: %s>", expected, response.StatusCode, response.Headers, response.Body)
+	}
+
+	// The export is kept as long as its file, which is named by the request.
+	if mock.Export.Key != "exports/devices-20200102T030405Z-request_test.ndjson" || mock.Export.ExpiresAt != 1578539045 {
+		t.Errorf("** Testing: Writing the export. ** \n \t<resulted export: %+v>", mock.Export)
+	}
+
+	request.RequestContext.RequestID = "failing_test"
+	response, _ = ExportDevices(request)
+	if response.StatusCode != 500 || response.Body != "Internal Server Error\nDatabase error." {
+		t.Errorf("** Testing: Failing to start an export. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+} // End of TestExportDevices function
+
+// ExportKey function in exportDevices.go signature: input: (format string, requestId string, now time.Time), output: (string)
+func TestExportKey(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("test", 3600))
+	if key := ExportKey(NDJSONFormat, "", now); key != "exports/devices-20200102T020405Z.ndjson" {
+		t.Errorf("** Naming an export ** \n \t<expected key: exports/devices-20200102T020405Z.ndjson> <resulted key: %s>", key)
+	}
+} // End of TestExportKey function
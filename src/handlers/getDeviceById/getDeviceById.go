@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fields"
 	"fmt"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -10,8 +12,13 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"history"
+	"net/http"
 	"os"
+	"time"
 	"types"
+	"versioning"
 )
 
 type AmazonWebServices struct {
@@ -41,7 +48,8 @@ func init() {
 }
 
 // Preparing DynamoDB Session and Calling DB's GetItem function inside.
-func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
+// When client asks for some fields only those are read, with deletedAt and version which the response needs anyway.
+func (self *AmazonWebServices) Get(id string, names []string) (*dynamodb.GetItemOutput, error) {
 	// Get desire table's name from OS's environmental varible.
 	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
 
@@ -54,6 +62,14 @@ func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
 			},
 		},
 	}
+	if len(names) != 0 {
+		expr, err := fields.Project(expression.NewBuilder(), names, "deletedAt", "version").Build()
+		if err != nil {
+			return nil, err
+		}
+		input.ProjectionExpression = expr.Projection()
+		input.ExpressionAttributeNames = expr.Names()
+	}
 
 	// Calling either GetItem function of interface, defined in getDeviceById_test.go file, or api with the input we've provided.
 	// In mock case, the GetItem function of getDeviceById_test.go will be called(interface.api)
@@ -62,6 +78,33 @@ func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
 	return result, err
 }
 
+// Preparing DynamoDB Session and Calling DB's Query function inside.
+// Reads the newest entry of the history of the device which has been written until the time.
+func (self *AmazonWebServices) AsOf(id string, at time.Time) (*dynamodb.QueryOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("HISTORY_TABLE_NAME"))
+
+	keyCondition := expression.Key("id").Equal(expression.Value(id)).
+		And(expression.Key("entry").LessThanEqual(expression.Value(history.LastKey(at))))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.QueryInput{
+		TableName:                 tableName,
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int64(1),
+	}
+
+	// Calling either Query function of interface, defined in getDeviceById_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Query(input)
+	return result, err
+}
+
 // The handler function which will be first started from main function.
 func GetDeviceById(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// The id which user has sent through GET method.
@@ -75,18 +118,50 @@ func GetDeviceById(request events.APIGatewayProxyRequest) (events.APIGatewayProx
 		}, nil
 	}
 
+	// Sparse fieldsets, i.e: "fields=id,name", only read and return the desired fields.
+	var names []string
+	if text, ok := request.QueryStringParameters["fields"]; ok {
+		var err error
+		names, err = fields.Parse(text, fields.DeviceFields)
+		// If fields are not suitable, return HTTP error code 400.
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       err.Error(),
+				StatusCode: 400,
+			}, nil
+		}
+	}
+
+	// Point-in-time reads, i.e: "asOf=2020-01-02T03:04:05Z", read the device as it was then from its history.
+	if text, ok := request.QueryStringParameters["asOf"]; ok {
+		at, err := ParseAsOf(text)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       err.Error(),
+				StatusCode: 400,
+			}, nil
+		}
+		result, err := TestAws.AsOf(id, at)
+		// Without any entry until then, the device as it is now tells whether it did not exist yet.
+		var live *dynamodb.GetItemOutput
+		if err == nil && len(result.Items) == 0 {
+			live, err = TestAws.Get(id, []string{"createdAt"})
+		}
+		return ValidateHistoryResult(result, live, err, names, at), nil
+	}
+
 	// Till now the user have provided an id in string type.
 	// Let's see whether it's existed on DB or not.
-	result, err := TestAws.Get(id)
+	result, err := TestAws.Get(id, names)
 
 	// Checking the result of the DynamoDB query.
-	ValidationResult := ValidateDatabaseResult(result, err)
+	ValidationResult := ValidateDatabaseResult(result, err, names)
 
 	// Return the result in ...
 	return ValidationResult, nil
 } // End of GetDeviceById function
 
-func ValidateDatabaseResult(result *dynamodb.GetItemOutput, err error) events.APIGatewayProxyResponse {
+func ValidateDatabaseResult(result *dynamodb.GetItemOutput, err error, names []string) events.APIGatewayProxyResponse {
 
 	// If an internal error have occurred in the database, return HTTP error code 500.
 	if err != nil {
@@ -110,16 +185,102 @@ func ValidateDatabaseResult(result *dynamodb.GetItemOutput, err error) events.AP
 	// Deserialization/Decoding "result.Item" to Go struct.
 	dynamodbattribute.UnmarshalMap(result.Item, &item)
 
-	// Serialization/Encoding item to JSON.
+	// Deleted devices are kept on DB until they are purged, but for clients they do not exist anymore.
+	if item.DeletedAt != "" {
+		return events.APIGatewayProxyResponse{
+			Body:       string("Desired device not found."),
+			StatusCode: 404,
+		}
+	}
+
+	// Serialization/Encoding item to JSON, with only the desired fields if client has asked for some.
 	FoundedDeviceJson, _ := json.Marshal(item)
+	if len(names) != 0 {
+		selected, _ := fields.Select(item, names)
+		FoundedDeviceJson, _ = json.Marshal(selected)
+	}
 
 	// Return founded item as JSON type with 200 HTTP status code.
+	// Its version goes to ETag header, so clients can send it back in If-Match header of their writes.
 	return events.APIGatewayProxyResponse{
 		Body:       string(FoundedDeviceJson),
+		Headers:    map[string]string{"ETag": versioning.ETag(item.Version)},
 		StatusCode: 200,
 	}
 } // End of ValidateDatabaseResult function
 
+// ParseAsOf reads the time of a point-in-time read. History is kept by the second, so fractions of a second are dropped.
+func ParseAsOf(text string) (time.Time, error) {
+	at, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		return time.Time{}, errors.New("Wrong format: asOf must be a time in RFC3339 format, i.e: 2020-01-02T03:04:05Z.")
+	}
+	return at.Truncate(time.Second), nil
+} // End of ParseAsOf function
+
+// ValidateHistoryResult answers with the newest entry of the history until the time. Without any, the live item, which
+// is only read then, tells whether the device had not been created yet or its history does not go back that far.
+func ValidateHistoryResult(result *dynamodb.QueryOutput, live *dynamodb.GetItemOutput, err error, names []string, at time.Time) events.APIGatewayProxyResponse {
+	// If an internal error have occurred in the database, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       string("Internal Server Error."),
+			StatusCode: 500,
+		}
+	}
+
+	entries := []types.HistoryEntry{}
+	// Deserialization/Decoding "result.Items" to Go structs.
+	dynamodbattribute.UnmarshalListOfMaps(result.Items, &entries)
+
+	// The device had been deleted or purged, or it did not exist yet: it is purged by now, or has been created
+	// after the time. Times are RFC3339 in UTC, so they are compared as text, and devices without createdAt
+	// have been added before the stamps.
+	notFound := len(entries) != 0 && (entries[0].Operation == history.Purge || entries[0].Device != nil && entries[0].Device.DeletedAt != "")
+	if len(entries) == 0 {
+		device := types.Device{}
+		dynamodbattribute.UnmarshalMap(live.Item, &device)
+		notFound = len(live.Item) == 0 || device.CreatedAt > at.UTC().Format(time.RFC3339)
+	}
+	if notFound {
+		return events.APIGatewayProxyResponse{
+			Body:       string("Desired device not found."),
+			StatusCode: 404,
+		}
+	}
+
+	// The device existed, but its history does not go back that far, or has been recorded before snapshots were
+	// kept, return HTTP error code 422.
+	if len(entries) == 0 || entries[0].Device == nil {
+		jsonResponse, _ := json.Marshal(types.Error{
+			Code:    "HistoryNotRecorded",
+			Message: fmt.Sprintf("The history of the device does not reach back to %s.", at.UTC().Format(time.RFC3339)),
+		})
+		return events.APIGatewayProxyResponse{
+			Body:       string(jsonResponse),
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			StatusCode: 422,
+		}
+	}
+	item := *entries[0].Device
+
+	// Serialization/Encoding item to JSON, with only the desired fields if client has asked for some.
+	FoundedDeviceJson, _ := json.Marshal(item)
+	if len(names) != 0 {
+		selected, _ := fields.Select(item, names)
+		FoundedDeviceJson, _ = json.Marshal(selected)
+	}
+
+	// A past version can not be written back, so it has no ETag. Memento-Datetime (RFC 7089) marks the response as
+	// historical, with the time this version of the device has been written.
+	changedAt, _ := time.Parse(time.RFC3339, entries[0].ChangedAt)
+	return events.APIGatewayProxyResponse{
+		Body:       string(FoundedDeviceJson),
+		Headers:    map[string]string{"Memento-Datetime": changedAt.UTC().Format(http.TimeFormat)},
+		StatusCode: 200,
+	}
+} // End of ValidateHistoryResult function
+
 func main() {
 	lambda.Start(GetDeviceById)
 }
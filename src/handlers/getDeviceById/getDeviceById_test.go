@@ -5,8 +5,13 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+	"types"
 )
 
 type TestCase struct {
@@ -18,17 +23,39 @@ type TestCase struct {
 	ExpectedBody           string
 	ExpectedStatusCode     int
 	Error                  error
+	Fields                 []string
 }
 
 // Mocking DynamoDB through dynamodbiface.
 type MockDynamoDB struct {
 	dynamodbiface.DynamoDBAPI
-	// Other return values expected to store, i.e: "payload map[string]string" or "err error"
+	// Last input the mocked GetItem function has received.
+	Input *dynamodb.GetItemInput
+	// Last input the mocked Query function has received.
+	QueryInput *dynamodb.QueryInput
+}
+
+// Custom Query function for overriding the Query of getDeviceById.go for using in test scenarios.
+// Mocking Query output to a history without entries.
+func (self *MockDynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	self.QueryInput = input
+	return new(dynamodb.QueryOutput), nil
 }
 
 // Custom GetItem function for overriding the GetItem of getDeviceById.go for using in test scenarios.
 // Mocking GetItem output to the a desire valid response.
 func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (output *dynamodb.GetItemOutput, err error) {
+	self.Input = input
+	// Like DynamoDB, a projection with the same path twice is rejected.
+	if input.ProjectionExpression != nil {
+		seen := map[string]bool{}
+		for _, path := range strings.Split(*input.ProjectionExpression, ",") {
+			if path = strings.TrimSpace(path); seen[path] {
+				return nil, errors.New("Invalid ProjectionExpression: Two document paths overlap with each other")
+			}
+			seen[path] = true
+		}
+	}
 	mockOutput := new(dynamodb.GetItemOutput)
 	inputID := input.Key["id"].S
 
@@ -86,11 +113,24 @@ func TestGet(t *testing.T) {
 
 	for _, test := range TestCases {
 		// Executing each test cases scenario.
-		response, _ := test_aws.Get(test.Input)
+		response, _ := test_aws.Get(test.Input, nil)
 		if len(response.GoString()) != len(test.ExpectedDatabaseOutput.GoString()) {
 			t.Errorf("%s \n \t<expected output: \n%s> \n<resulted output: \n%s>", test.Name, test.ExpectedDatabaseOutput.GoString(), response.GoString())
 		}
 	}
+
+	// Only the desired fields are read, with the ones the response needs anyway.
+	mock := &MockDynamoDB{}
+	test_aws.DynamoDB = mock
+	test_aws.Get("id_test", []string{"id", "name"})
+	names := []string{}
+	for _, name := range mock.Input.ExpressionAttributeNames {
+		names = append(names, *name)
+	}
+	sort.Strings(names)
+	if mock.Input.ProjectionExpression == nil || strings.Join(names, ",") != "deletedAt,id,name,version" {
+		t.Errorf("** Requested fields are projected. ** \n \t<resulted input: \n%s>", mock.Input.GoString())
+	}
 } // End of TestGet function
 
 // GetDeviceById function in getDeviceById.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
@@ -111,6 +151,24 @@ func TestGetDeviceById(t *testing.T) {
 			ExpectedStatusCode: 500,
 		},
 
+		{
+			Name:               "** Testing: Unknown field in fields. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, QueryStringParameters: map[string]string{"fields": "id,color"}},
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Empty field in fields. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, QueryStringParameters: map[string]string{"fields": "id,,name"}},
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Wrong format of asOf. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, QueryStringParameters: map[string]string{"asOf": "2020-01-02"}},
+			ExpectedStatusCode: 400,
+		},
+
 		//{
 		//// In Testing environment, as we don't access AWS's OS environment variable and other real world parameters, can not reach to
 		//// HTTP code 200 point in here, unless we prepare a mock server for it.
@@ -131,6 +189,12 @@ func TestGetDeviceById(t *testing.T) {
 		}
 	}
 
+	// A field the response needs anyway is read once, DynamoDB would reject it twice.
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+	response, _ := GetDeviceById(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, QueryStringParameters: map[string]string{"fields": "version"}})
+	if response.StatusCode != 200 {
+		t.Errorf("** Testing: Field the response needs anyway. ** \n \t<expected error-code: 200> <resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
 } // End of TestGetDeviceById function
 
 // ValidateDatabaseResult function in getDeviceById.go signature: input: (result *dynamodb.GetItemOutput, err error), output: (events.APIGatewayProxyResponse)
@@ -149,6 +213,19 @@ func TestValidateDatabaseResult(t *testing.T) {
 		},
 	)
 
+	// Deleted devices are still on DB, until they are purged.
+	DeletedOutput := dynamodb.GetItemOutput{}
+	DeletedOutput.SetItem(
+		map[string]*dynamodb.AttributeValue{
+			"id":          &dynamodb.AttributeValue{S: aws.String("id_test")},
+			"deviceModel": &dynamodb.AttributeValue{S: aws.String("deviceModel_test")},
+			"name":        &dynamodb.AttributeValue{S: aws.String("name_test")},
+			"note":        &dynamodb.AttributeValue{S: aws.String("note_test")},
+			"serial":      &dynamodb.AttributeValue{S: aws.String("serial_test")},
+			"deletedAt":   &dynamodb.AttributeValue{S: aws.String("2020-01-02T03:04:05Z")},
+		},
+	)
+
 	TestCases := []TestCase{
 
 		{
@@ -168,20 +245,189 @@ func TestValidateDatabaseResult(t *testing.T) {
 			ExpectedStatusCode: 404,
 		},
 
+		{
+			Name:               "** Database Returns deleted device **",
+			MockDatabaseOutput: DeletedOutput,
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
 		{
 			Name:               "** Database Returns founded device **",
 			MockDatabaseOutput: MockOutput,
 			ExpectedBody:       "{\"id\":\"id_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\"}",
 			ExpectedStatusCode: 200,
 		},
+
+		{
+			Name:               "** Database Returns desired fields of founded device **",
+			MockDatabaseOutput: MockOutput,
+			Fields:             []string{"name", "id"},
+			ExpectedBody:       "{\"id\":\"id_test\",\"name\":\"name_test\"}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	// Founded devices carry their version in ETag header, devices written before versioning have version 0.
+	response := ValidateDatabaseResult(&MockOutput, nil, nil)
+	if response.Headers["ETag"] != "\"0\"" {
+		t.Errorf("** Database Returns founded device ** \n \t<expected ETag: %s> <resulted ETag: %s>", "\"0\"", response.Headers["ETag"])
 	}
 
 	for _, test := range TestCases {
 		// Executing each test cases scenario.
-		response := ValidateDatabaseResult(&test.MockDatabaseOutput, test.Error)
+		response := ValidateDatabaseResult(&test.MockDatabaseOutput, test.Error, test.Fields)
 
 		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
 			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
 		}
 	}
 }
+
+// AsOf function in getDeviceById.go signature: input: (id string, at time.Time), output: (*dynamodb.QueryOutput, error)
+func TestAsOf(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	// Only the newest entry up to the time is read, entries written in the same second included.
+	test_aws.AsOf("id_test", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	input := mock.QueryInput
+	if !strings.Contains(*input.KeyConditionExpression, "<=") || !strings.Contains(input.GoString(), "2020-01-02T03:04:05Z#09223372036854775807") || *input.ScanIndexForward || *input.Limit != 1 {
+		t.Errorf("** Reading the history up to a time ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+} // End of TestAsOf function
+
+// ParseAsOf function in getDeviceById.go signature: input: (text string), output: (time.Time, error)
+func TestParseAsOf(t *testing.T) {
+	at, err := ParseAsOf("2020-01-02T04:04:05.9+01:00")
+	if err != nil || !at.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("** Parsing asOf ** \n \t<expected time: 2020-01-02T03:04:05Z> <resulted time: %s> <resulted error: %v>", at, err)
+	}
+
+	if _, err := ParseAsOf("yesterday"); err == nil || err.Error() != "Wrong format: asOf must be a time in RFC3339 format, i.e: 2020-01-02T03:04:05Z." {
+		t.Errorf("** Parsing a wrong asOf ** \n \t<resulted error: %v>", err)
+	}
+} // End of TestParseAsOf function
+
+// ValidateHistoryResult function in getDeviceById.go signature: input: (result *dynamodb.QueryOutput, live *dynamodb.GetItemOutput, err error, names []string, at time.Time), output: (events.APIGatewayProxyResponse)
+func TestValidateHistoryResult(t *testing.T) {
+	device := types.Device{ID: "id_test", DeviceModel: "deviceModel_test", Name: "name_test", Note: "note_test", Serial: "serial_test", Version: 2}
+	deleted := device
+	deleted.DeletedAt = "2020-01-02T03:04:05Z"
+
+	Output := func(entry types.HistoryEntry) *dynamodb.QueryOutput {
+		item, _ := dynamodbattribute.MarshalMap(entry)
+		return &dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{item}}
+	}
+	Live := func(createdAt string) *dynamodb.GetItemOutput {
+		item := map[string]*dynamodb.AttributeValue{"id": {S: aws.String("id_test")}}
+		if createdAt != "" {
+			item["createdAt"] = &dynamodb.AttributeValue{S: aws.String(createdAt)}
+		}
+		return &dynamodb.GetItemOutput{Item: item}
+	}
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tooShort := "{\"code\":\"HistoryNotRecorded\",\"message\":\"The history of the device does not reach back to 2020-01-02T03:04:05Z.\"}"
+
+	TestCases := []struct {
+		Name               string
+		Output             *dynamodb.QueryOutput
+		Live               *dynamodb.GetItemOutput
+		Error              error
+		Fields             []string
+		ExpectedBody       string
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "** Database Unexpected Error **",
+			Output:             &dynamodb.QueryOutput{},
+			Error:              errors.New("unexpected Error has occurred"),
+			ExpectedBody:       "Internal Server Error.",
+			ExpectedStatusCode: 500,
+		},
+
+		{
+			Name:               "** Device did not exist yet **",
+			Output:             &dynamodb.QueryOutput{},
+			Live:               Live("2020-01-02T03:04:06Z"),
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Device does not exist anymore **",
+			Output:             &dynamodb.QueryOutput{},
+			Live:               &dynamodb.GetItemOutput{},
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Device existed before its history **",
+			Output:             &dynamodb.QueryOutput{},
+			Live:               Live("2020-01-02T03:04:05Z"),
+			ExpectedBody:       tooShort,
+			ExpectedStatusCode: 422,
+		},
+
+		{
+			Name:               "** Device added before the stamps **",
+			Output:             &dynamodb.QueryOutput{},
+			Live:               Live(""),
+			ExpectedBody:       tooShort,
+			ExpectedStatusCode: 422,
+		},
+
+		{
+			Name:               "** Device was there before snapshots were kept **",
+			Output:             Output(types.HistoryEntry{Version: 2, ChangedAt: "2020-01-02T03:04:05Z", Operation: "update"}),
+			ExpectedBody:       tooShort,
+			ExpectedStatusCode: 422,
+		},
+
+		{
+			Name:               "** Device had been purged **",
+			Output:             Output(types.HistoryEntry{Version: 3, ChangedAt: "2020-01-02T03:04:05Z", Operation: "purge"}),
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Device had been deleted **",
+			Output:             Output(types.HistoryEntry{Version: 3, ChangedAt: "2020-01-02T03:04:05Z", Operation: "delete", Device: &deleted}),
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Device was there **",
+			Output:             Output(types.HistoryEntry{Version: 2, ChangedAt: "2020-01-02T03:04:05Z", Operation: "update", Device: &device}),
+			ExpectedBody:       "{\"id\":\"id_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\",\"version\":2}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Desired fields of the device as it was **",
+			Output:             Output(types.HistoryEntry{Version: 2, ChangedAt: "2020-01-02T03:04:05Z", Operation: "update", Device: &device}),
+			Fields:             []string{"name", "id"},
+			ExpectedBody:       "{\"id\":\"id_test\",\"name\":\"name_test\"}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range TestCases {
+		// Executing each test cases scenario.
+		response := ValidateHistoryResult(test.Output, test.Live, test.Error, test.Fields, at)
+
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// A past version is marked as historical, and can not be sent back in If-Match.
+	response := ValidateHistoryResult(Output(types.HistoryEntry{Version: 2, ChangedAt: "2020-01-02T03:04:05Z", Operation: "update", Device: &device}), nil, nil, nil, at)
+	if response.Headers["Memento-Datetime"] != "Thu, 02 Jan 2020 03:04:05 GMT" || response.Headers["ETag"] != "" {
+		t.Errorf("** Device was there ** \n \t<expected Memento-Datetime: Thu, 02 Jan 2020 03:04:05 GMT> <resulted headers: %v>", response.Headers)
+	}
+} // End of TestValidateHistoryResult function
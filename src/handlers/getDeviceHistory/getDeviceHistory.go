@@ -0,0 +1,197 @@
+package main
+
+import (
+	"cursor"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"os"
+	"strconv"
+	"types"
+)
+
+// Number of history entries returned when client does not ask for a specific page size.
+const DefaultLimit = 25
+
+// Server enforced maximum page size, bigger limits will be lowered to it.
+const MaxLimit = 100
+
+// Cursors of this endpoint can not be used on other list endpoints, nor on the history of another device.
+const CursorScope = "history "
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's Query function inside.
+// Entries are sorted by their keys, which start with the time of the change, so reading backwards gives the newest first.
+func (self *AmazonWebServices) History(id string, limit int64, startKey map[string]*dynamodb.AttributeValue) (*dynamodb.QueryOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("HISTORY_TABLE_NAME"))
+
+	keyCondition := expression.Key("id").Equal(expression.Value(id))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.QueryInput{
+		TableName:                 tableName,
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int64(limit),
+	}
+	// Continue right after the last entry of the previous page.
+	if len(startKey) != 0 {
+		input.ExclusiveStartKey = startKey
+	}
+
+	// Calling either Query function of interface, defined in getDeviceHistory_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Query(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+// The history outlives the device, so the history of a purged device can still be read.
+func GetDeviceHistory(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	id := request.PathParameters["id"]
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : id",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// First & foremost we have to validate user input.
+	limit, startKey, err := ValidateInputs(id, request)
+	// A missing cursor secret is a misconfiguration of the server, not a mistake of the client.
+	if err == cursor.ErrMissingSecret {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}, nil
+	}
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	result, err := TestAws.History(id, limit, startKey)
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Checking the result of the DynamoDB query.
+	return ValidateDatabaseResult(id, result), nil
+} // End of GetDeviceHistory function
+
+func ValidateInputs(id string, request events.APIGatewayProxyRequest) (int64, map[string]*dynamodb.AttributeValue, error) {
+	var limit int64 = DefaultLimit
+
+	if value, ok := request.QueryStringParameters["limit"]; ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			return 0, nil, errors.New("Wrong format: limit must be a positive number.")
+		}
+		limit = parsed
+	}
+
+	// Bigger pages than the maximum are not an error, they will be shortened.
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	token := request.QueryStringParameters["cursor"]
+	if token == "" {
+		return limit, nil, nil
+	}
+
+	startKey, err := cursor.Decode(CursorScope+id, token)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Everything looks fine, return page size and where the page starts.
+	return limit, startKey, nil
+} // End of ValidateInputs function.
+
+func ValidateDatabaseResult(id string, result *dynamodb.QueryOutput) events.APIGatewayProxyResponse {
+	page := types.HistoryPage{Entries: []types.HistoryEntry{}}
+
+	// Deserialization/Decoding "result.Items" to Go structs.
+	err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &page.Entries)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}
+	}
+	// DynamoDB keeps no changes as NULL, clients get them as an empty list.
+	for i := range page.Entries {
+		if page.Entries[i].Changes == nil {
+			page.Entries[i].Changes = []types.Change{}
+		}
+	}
+
+	// No LastEvaluatedKey means the oldest entry has been reached and the cursor stays empty.
+	page.NextCursor, err = cursor.Encode(CursorScope+id, result.LastEvaluatedKey)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}
+	}
+
+	// Serialization/Encoding page to JSON.
+	jsonResponse, _ := json.Marshal(page)
+
+	// Return founded page as JSON type with 200 HTTP status code.
+	return events.APIGatewayProxyResponse{
+		Body:       string(jsonResponse),
+		StatusCode: 200,
+	}
+} // End of ValidateDatabaseResult function
+
+func main() {
+	lambda.Start(GetDeviceHistory)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"cursor"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+// Custom Query function for overriding the Query of getDeviceHistory.go for using in test scenarios.
+// Mocking Query output to a page with the newest entry of "id_test", followed by a last page with a write
+// which has not changed any field. Other devices have no history.
+func (self *MockDynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	mockOutput := new(dynamodb.QueryOutput)
+
+	if *input.ExpressionAttributeValues[":0"].S == "id_test" && input.ExclusiveStartKey == nil && !*input.ScanIndexForward {
+		mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{{
+			"id":        &dynamodb.AttributeValue{S: aws.String("id_test")},
+			"entry":     &dynamodb.AttributeValue{S: aws.String("2020-01-02T03:04:05Z#00000000000000000002")},
+			"version":   &dynamodb.AttributeValue{N: aws.String("2")},
+			"changedAt": &dynamodb.AttributeValue{S: aws.String("2020-01-02T03:04:05Z")},
+			"changedBy": &dynamodb.AttributeValue{S: aws.String("bob")},
+			"operation": &dynamodb.AttributeValue{S: aws.String("update")},
+			"changes": &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{{M: map[string]*dynamodb.AttributeValue{
+				"field":  {S: aws.String("serial")},
+				"before": {S: aws.String("A1")},
+				"after":  {S: aws.String("A2")},
+			}}}},
+		}})
+		mockOutput.SetLastEvaluatedKey(HistoryKey())
+	} else if *input.ExpressionAttributeValues[":0"].S == "id_test" && !*input.ScanIndexForward {
+		mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{{
+			"id":        &dynamodb.AttributeValue{S: aws.String("id_test")},
+			"entry":     &dynamodb.AttributeValue{S: aws.String("2020-01-02T03:04:05Z#00000000000000000001")},
+			"version":   &dynamodb.AttributeValue{N: aws.String("1")},
+			"changedAt": &dynamodb.AttributeValue{S: aws.String("2020-01-02T03:04:05Z")},
+			"changedBy": &dynamodb.AttributeValue{S: aws.String("alice")},
+			"operation": &dynamodb.AttributeValue{S: aws.String("update")},
+			"changes":   &dynamodb.AttributeValue{NULL: aws.Bool(true)},
+		}})
+	}
+	return mockOutput, nil
+}
+
+func HistoryKey() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":    {S: aws.String("id_test")},
+		"entry": {S: aws.String("2020-01-02T03:04:05Z#00000000000000000002")},
+	}
+}
+
+// GetDeviceHistory function in getDeviceHistory.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestGetDeviceHistory(t *testing.T) {
+	os.Setenv("CURSOR_SECRET", "secret_test")
+	nextCursor, _ := cursor.Encode(CursorScope+"id_test", HistoryKey())
+	otherCursor, _ := cursor.Encode(CursorScope+"other_test", HistoryKey())
+
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Missing id. **",
+			Request:            events.APIGatewayProxyRequest{},
+			ExpectedBody:       "Missing field : id",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Wrong limit. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, QueryStringParameters: map[string]string{"limit": "none"}},
+			ExpectedBody:       "Wrong format: limit must be a positive number.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Cursor of the history of another device. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, QueryStringParameters: map[string]string{"cursor": otherCursor}},
+			ExpectedBody:       "Invalid cursor.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: First page. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}},
+			ExpectedBody:       "{\"entries\":[{\"version\":2,\"changedAt\":\"2020-01-02T03:04:05Z\",\"changedBy\":\"bob\",\"operation\":\"update\",\"changes\":[{\"field\":\"serial\",\"before\":\"A1\",\"after\":\"A2\"}]}],\"nextCursor\":\"" + nextCursor + "\"}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Last page. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, QueryStringParameters: map[string]string{"cursor": nextCursor}},
+			ExpectedBody:       "{\"entries\":[{\"version\":1,\"changedAt\":\"2020-01-02T03:04:05Z\",\"changedBy\":\"alice\",\"operation\":\"update\",\"changes\":[]}]}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Device without history. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "missing_test"}},
+			ExpectedBody:       "{\"entries\":[]}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := GetDeviceHistory(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestGetDeviceHistory function
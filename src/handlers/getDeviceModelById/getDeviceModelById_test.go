@@ -0,0 +1,73 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+// Custom GetItem function for overriding the GetItem of getDeviceModelById.go for using in test scenarios.
+// Only "model_test" exists on the mocked DB.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	MockOutput := new(dynamodb.GetItemOutput)
+	if *input.Key["id"].S == "model_test" {
+		MockOutput.SetItem(
+			map[string]*dynamodb.AttributeValue{
+				"id":           &dynamodb.AttributeValue{S: aws.String("model_test")},
+				"manufacturer": &dynamodb.AttributeValue{S: aws.String("manufacturer_test")},
+				"modelName":    &dynamodb.AttributeValue{S: aws.String("modelName_test")},
+			},
+		)
+	}
+	return MockOutput, nil
+}
+
+// GetDeviceModelById function in getDeviceModelById.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestGetDeviceModelById(t *testing.T) {
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty id input. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": ""}},
+			ExpectedBody:       "Missing field : modelId",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Device model does not exist. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "not_existed"}},
+			ExpectedBody:       "Desired device model not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Device model exists. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "model_test"}},
+			ExpectedBody:       "{\"id\":\"model_test\",\"manufacturer\":\"manufacturer_test\",\"modelName\":\"modelName_test\"}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := GetDeviceModelById(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestGetDeviceModelById function
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"clock"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"os"
+	"time"
+	"types"
+)
+
+// How long the link to a succeeded export can be used.
+const LinkExpiry = 15 * time.Minute
+
+// Timeout of runExport. Lambda stops an export which runs longer, before it can tell that it has failed.
+const RunTimeout = 15 * time.Minute
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+	S3       s3iface.S3API
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+		var s3svc *s3.S3 = s3.New(Aws.Session)
+		Aws.S3 = s3iface.S3API(s3svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside.
+// Exports are read consistently, so clients see the status right after runExport has written it.
+func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("EXPORTS_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	// Calling either GetItem function of interface, defined in getExport_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	return result, err
+}
+
+// Presign returns a link for downloading the export, which works without credentials until it expires.
+func (self *AmazonWebServices) Presign(key string) (string, error) {
+	// Get desire bucket's name from OS's environmental varible.
+	bucket := aws.String(os.Getenv("EXPORTS_BUCKET_NAME"))
+
+	request, _ := self.S3.GetObjectRequest(&s3.GetObjectInput{Bucket: bucket, Key: aws.String(key)})
+	return request.Presign(LinkExpiry)
+}
+
+// The handler function which will be first started from main function.
+// Clients read the export which exportDevices has started until it has succeeded, then it has a link to its file.
+func GetExport(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which user has sent through GET method.
+	id := request.PathParameters["exportId"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : exportId",
+			StatusCode: 404,
+		}, nil
+	}
+
+	result, err := TestAws.Get(id)
+	// If an internal error have occurred in the database, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// If no export have been founded, or it has expired, return HTTP error code 404.
+	if len(result.Item) == 0 {
+		return events.APIGatewayProxyResponse{
+			Body:       "Desired export not found.",
+			StatusCode: 404,
+		}, nil
+	}
+
+	export := types.Export{}
+	// Deserialization/Decoding "result.Item" to Go struct.
+	dynamodbattribute.UnmarshalMap(result.Item, &export)
+	export = Timeout(export, clock.Now())
+
+	if export.Status == types.ExportSucceeded {
+		export.URL, err = TestAws.Presign(export.Key)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error.",
+				StatusCode: 500,
+			}, nil
+		}
+	}
+
+	// Serialization/Encoding export to JSON.
+	jsonResponse, _ := json.Marshal(export)
+
+	// Return founded export as JSON type with 200 HTTP status code, whatever its status is.
+	return events.APIGatewayProxyResponse{
+		Body:       string(jsonResponse),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		StatusCode: 200,
+	}, nil
+} // End of GetExport function
+
+// Timeout tells an export which is still running after the timeout of runExport as failed, as Lambda has stopped it.
+func Timeout(export types.Export, now time.Time) types.Export {
+	started, err := time.Parse(time.RFC3339, export.StartedAt)
+	if export.Status == types.ExportRunning && err == nil && now.Sub(started) > RunTimeout {
+		export.Status = types.ExportFailed
+		export.FinishedAt = clock.Format(started.Add(RunTimeout))
+	}
+	return export
+} // End of Timeout function
+
+func main() {
+	lambda.Start(GetExport)
+}
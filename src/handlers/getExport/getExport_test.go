@@ -0,0 +1,108 @@
+package main
+
+import (
+	"clock"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"types"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+// Exports on the mocked DB, by their ids.
+var MockExports = map[string]types.Export{
+	"pending_test": {ID: "pending_test", Format: "csv", Status: types.ExportPending, CreatedAt: "2020-01-02T03:00:00Z", CreatedBy: "alice", Key: "exports/pending_test.csv"},
+	"running_test": {ID: "running_test", Format: "csv", Status: types.ExportRunning, CreatedAt: "2020-01-02T03:00:00Z", CreatedBy: "alice", StartedAt: "2020-01-02T03:00:01Z", Key: "exports/running_test.csv"},
+	"stopped_test": {ID: "stopped_test", Format: "csv", Status: types.ExportRunning, CreatedAt: "2020-01-02T02:00:00Z", CreatedBy: "alice", StartedAt: "2020-01-02T02:00:01Z", Key: "exports/stopped_test.csv"},
+	"done_test":    {ID: "done_test", Format: "ndjson", Status: types.ExportSucceeded, CreatedAt: "2020-01-02T03:00:00Z", CreatedBy: "alice", StartedAt: "2020-01-02T03:00:01Z", FinishedAt: "2020-01-02T03:02:00Z", Key: "exports/done_test.ndjson"},
+}
+
+// Custom GetItem function for overriding the GetItem of getExport.go for using in test scenarios.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	mockOutput := new(dynamodb.GetItemOutput)
+	// Exports have to be read consistently, so a status is seen right after it has been written.
+	if export, ok := MockExports[*input.Key["id"].S]; ok && input.ConsistentRead != nil && *input.ConsistentRead {
+		mockOutput.Item, _ = dynamodbattribute.MarshalMap(export)
+	}
+	return mockOutput, nil
+}
+
+// GetExport function in getExport.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestGetExport(t *testing.T) {
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	os.Setenv("EXPORTS_BUCKET_NAME", "exports_test")
+	// Links are signed locally, so a client with static credentials is enough.
+	s3Session := session.Must(session.NewSession(&aws.Config{Region: aws.String("us-east-2"), Credentials: credentials.NewStaticCredentials("id", "secret", "")}))
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}, S3: s3.New(s3Session)}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty id input. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"exportId": ""}},
+			ExpectedBody:       "Missing field : exportId",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Export does not exist. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"exportId": "not_existed"}},
+			ExpectedBody:       "Desired export not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Pending export. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"exportId": "pending_test"}},
+			ExpectedBody:       "{\"id\":\"pending_test\",\"format\":\"csv\",\"status\":\"pending\",\"createdAt\":\"2020-01-02T03:00:00Z\",\"createdBy\":\"alice\"}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Running export. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"exportId": "running_test"}},
+			ExpectedBody:       "{\"id\":\"running_test\",\"format\":\"csv\",\"status\":\"running\",\"createdAt\":\"2020-01-02T03:00:00Z\",\"createdBy\":\"alice\",\"startedAt\":\"2020-01-02T03:00:01Z\"}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Export stopped by the timeout. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"exportId": "stopped_test"}},
+			ExpectedBody:       "{\"id\":\"stopped_test\",\"format\":\"csv\",\"status\":\"failed\",\"createdAt\":\"2020-01-02T02:00:00Z\",\"createdBy\":\"alice\",\"startedAt\":\"2020-01-02T02:00:01Z\",\"finishedAt\":\"2020-01-02T02:15:01Z\"}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := GetExport(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// A succeeded export has a signed link to its file.
+	response, _ := GetExport(events.APIGatewayProxyRequest{PathParameters: map[string]string{"exportId": "done_test"}})
+	if response.StatusCode != 200 || !strings.Contains(response.Body, "\"status\":\"succeeded\"") || !strings.Contains(response.Body, "exports_test") || !strings.Contains(response.Body, "done_test.ndjson") || !strings.Contains(response.Body, "X-Amz-Signature=") {
+		t.Errorf("** Testing: Succeeded export. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+} // End of TestGetExport function
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"actor"
+	"devicemodels"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"headers"
+	"ids"
+	"io"
+	"lifecycle"
+	"os"
+	"strings"
+	"types"
+	"versioning"
+)
+
+// Most rows accepted in one file, so an import always finishes within the Lambda timeout.
+const MaxRows = 500
+
+// Media types of the files which can be imported.
+const (
+	CSVMediaType    = "text/csv"
+	NDJSONMediaType = "application/x-ndjson"
+)
+
+// What happens to the devices of the file.
+const (
+	// Only new devices are added, rows of existing devices fail.
+	CreateOnly = "create-only"
+	// New devices are added and existing ones are replaced.
+	Upsert = "upsert"
+	// Rows are only validated, nothing is written.
+	DryRun = "dry-run"
+)
+
+// Columns which a CSV header can have, the JSON names of the fields clients can set.
+var Columns = map[string]bool{
+	"id":          true,
+	"deviceModel": true,
+	"name":        true,
+	"note":        true,
+	"serial":      true,
+}
+
+// Struct containing one row of the file, as a JSON object, and the line it starts on.
+type Row struct {
+	Line int
+	JSON string
+	// Set when the row could not be read as an object, it's reported instead of validating the row.
+	Err error
+}
+
+// Struct containing the outcome of one row of the file, for marshalling/unmarshalling.
+type ImportResult struct {
+	Line   int           `json:"line"`
+	ID     string        `json:"id,omitempty"`
+	Status int           `json:"status"`
+	Device *types.Device `json:"device,omitempty"`
+	Error  *types.Error  `json:"error,omitempty"`
+}
+
+// Struct containing the outcome of every row, in the order of the file.
+type ImportResponse struct {
+	Mode    string         `json:"mode"`
+	Results []ImportResult `json:"results"`
+}
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's TransactWriteItems function inside, through devicemodels.Write.
+// Like adding a single device, an existing item with the same id is never overwritten. The device model is checked
+// in the same transaction, so it can not be deleted while the device is added.
+func (self *AmazonWebServices) Create(device types.Device) (*dynamodb.TransactWriteItemsOutput, error) {
+	// Get table name from OS's environment
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	// Every device starts with the first version and in the first state of its lifecycle, and is kept for looking it up by its serial.
+	device.Version = 1
+	device.Status = lifecycle.Initial
+	device.SerialKey = types.NormalizeSerial(device.Serial)
+
+	// Serialization/Encoding "device" in "item" for using in DynamoDB functions.
+	item, _ := dynamodbattribute.MarshalMap(device)
+
+	var write = &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			Item:                item,
+			TableName:           tableName,
+			ConditionExpression: aws.String("attribute_not_exists(id)"),
+		},
+	}
+	// Calling either TransactWriteItems function of interface, defined in importDevices_test.go file, or api with the input we've provided.
+	result, err := devicemodels.Write(self.DynamoDB, write, device)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's TransactWriteItems function inside, through devicemodels.Update.
+// An existing device is replaced with a new version, it keeps its creator and its state. Deleted devices are
+// never brought back by an import, the condition fails for them. New devices are added by Create instead.
+// The row may give the device another device model, so the model is checked in the same transaction.
+func (self *AmazonWebServices) Replace(device types.Device) (*dynamodb.UpdateItemOutput, error) {
+	// Get table name from OS's environment
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	update := expression.Set(expression.Name("deviceModel"), expression.Value(device.DeviceModel)).
+		Set(expression.Name("name"), expression.Value(device.Name)).
+		Set(expression.Name("note"), expression.Value(device.Note)).
+		Set(expression.Name("serial"), expression.Value(device.Serial)).
+		Set(expression.Name("serialKey"), expression.Value(types.NormalizeSerial(device.Serial)))
+	// The device is replaced as a whole like by Request 4, so a row without tags or attributes leaves none.
+	if len(device.Tags) != 0 {
+		update = update.Set(expression.Name("tags"), expression.Value(device.Tags))
+	} else {
+		update = update.Remove(expression.Name("tags"))
+	}
+	if len(device.Attributes) != 0 {
+		update = update.Set(expression.Name("attributes"), expression.Value(device.Attributes))
+	} else {
+		update = update.Remove(expression.Name("attributes"))
+	}
+	// Devices added before creators were stamped are left without one, the import has not created them.
+	update = actor.StampAt(update, device.UpdatedBy, device.UpdatedAt)
+	condition := expression.AttributeExists(expression.Name("id")).
+		And(expression.AttributeNotExists(expression.Name("deletedAt")))
+
+	expr, err := expression.NewBuilder().WithUpdate(versioning.Increment(update)).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(device.ID),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	}
+	// Calling either TransactWriteItems function of interface, defined in importDevices_test.go file, or api with the input we've provided.
+	result, err := devicemodels.Update(self.DynamoDB, input, device, true)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+// Every row gets its own status with its line number, so one bad row does not fail the whole file.
+func ImportDevices(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Only CSV and NDJSON files are understood, return HTTP error code 415 for anything else.
+	mediaType := headers.MediaType(request.Headers)
+	if mediaType != CSVMediaType && mediaType != NDJSONMediaType {
+		return events.APIGatewayProxyResponse{
+			Body:       "Unsupported media type: Content-Type must be " + CSVMediaType + " or " + NDJSONMediaType + ".",
+			StatusCode: 415,
+		}, nil
+	}
+
+	// First & foremost we have to validate user input.
+	mode, rows, err := ValidateInputs(request, mediaType)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	results := make([]ImportResult, len(rows))
+	firstLines := map[string]int{}
+	// Devices of a file mostly share a few models, each of them is only read once.
+	models := devicemodels.NewCache(TestAws.DynamoDB)
+
+	for index, row := range rows {
+		results[index].Line = row.Line
+
+		NewDevice, err := ValidateDevice(row)
+		results[index].ID = NewDevice.ID
+		if err != nil {
+			results[index].Status = 400
+			results[index].Error = &types.Error{Code: "InvalidDevice", Message: err.Error()}
+			continue
+		}
+
+		// A device is written once per file, later rows would silently win over earlier ones.
+		if line, ok := firstLines[NewDevice.ID]; ok {
+			results[index].Status = 409
+			results[index].Error = &types.Error{Code: "DuplicateId", Message: fmt.Sprintf("The id %s is used by an earlier row on line %d.", NewDevice.ID, line)}
+			continue
+		}
+		firstLines[NewDevice.ID] = row.Line
+
+		// The device model has to exist, so typos do not create phantom models, and attributes have to match its schema.
+		modelErr := models.Check(NewDevice)
+		if devicemodels.IsClientError(modelErr) {
+			results[index].Status = 400
+			results[index].Error = &types.Error{Code: "InvalidDevice", Message: modelErr.Error()}
+			continue
+		}
+		if modelErr != nil {
+			results[index].Status = 500
+			results[index].Error = &types.Error{Code: "InternalError", Message: "Internal Server Error\nDatabase error."}
+			continue
+		}
+
+		// Stamped with when and by whom it has been written, for auditing and the history of the device.
+		NewDevice = actor.New(NewDevice, actor.From(request))
+		results[index] = WriteDevice(mode, NewDevice, results[index])
+	}
+
+	// Serialization/Encoding results to JSON.
+	jsonResponse, _ := json.Marshal(ImportResponse{Mode: mode, Results: results})
+
+	// Returns the results with HTTP 207 Multi-Status, as each row has its own status.
+	return events.APIGatewayProxyResponse{
+		Body:       string(jsonResponse),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		StatusCode: 207,
+	}, nil
+} // End of ImportDevices function
+
+// Writes a valid device as the mode asks for, and fills the result of its row.
+func WriteDevice(mode string, device types.Device, result ImportResult) ImportResult {
+	var err error
+	switch mode {
+	case DryRun:
+		// Nothing is written, the device is returned as it would be written.
+		result.Status = 200
+		result.Device = &device
+		return result
+
+	case Upsert:
+		// The device is added like in create-only mode, and only replaced when it already exists.
+		// So devices are only stamped with a creator when the import really creates them.
+		_, err = TestAws.Create(device)
+		if err == nil {
+			device.Version = 1
+			device.Status = lifecycle.Initial
+			result.Status = 201
+			result.Device = &device
+			return result
+		}
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+			break
+		}
+
+		var output *dynamodb.UpdateItemOutput
+		output, err = TestAws.Replace(device)
+		if err == nil {
+			// The new attributes have the new version of the device, with its creator and its state.
+			ReplacedDevice := types.Device{}
+			dynamodbattribute.UnmarshalMap(output.Attributes, &ReplacedDevice)
+			result.Status = 200
+			result.Device = &ReplacedDevice
+			return result
+		}
+
+	default:
+		_, err = TestAws.Create(device)
+		if err == nil {
+			device.Version = 1
+			device.Status = lifecycle.Initial
+			result.Status = 201
+			result.Device = &device
+			return result
+		}
+	}
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		result.Status = 409
+		if mode == Upsert {
+			result.Error = &types.Error{Code: "DeviceDeleted", Message: "The device " + device.ID + " has been deleted, restore it before importing it again."}
+		} else {
+			result.Error = &types.Error{Code: "DeviceAlreadyExists", Message: "A device with id " + device.ID + " already exists, use mode=upsert to replace it."}
+		}
+		return result
+	}
+
+	// The device model has been deleted since it was checked, or is being deleted.
+	if devicemodels.IsClientError(err) {
+		result.Status = 400
+		result.Error = &types.Error{Code: "InvalidDevice", Message: err.Error()}
+		return result
+	}
+
+	result.Status = 500
+	result.Error = &types.Error{Code: "InternalError", Message: "Internal Server Error\nDatabase error."}
+	return result
+} // End of WriteDevice function
+
+// Checks the mode and reads the rows of the file. Rows themselves are validated one by one.
+func ValidateInputs(request events.APIGatewayProxyRequest, mediaType string) (string, []Row, error) {
+	mode := CreateOnly
+	if value, ok := request.QueryStringParameters["mode"]; ok {
+		if value != CreateOnly && value != Upsert && value != DryRun {
+			return "", nil, errors.New("Wrong format: mode must be " + CreateOnly + ", " + Upsert + " or " + DryRun + ".")
+		}
+		mode = value
+	}
+
+	body := request.Body
+	// API Gateway encodes bodies of binary media types, which CSV files are when it's configured so.
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return "", nil, errors.New("Wrong format: Body is not valid base64.")
+		}
+		body = string(decoded)
+	}
+	// Spreadsheet programs start UTF-8 files with a byte order mark.
+	body = strings.TrimPrefix(body, "\ufeff")
+
+	if strings.TrimSpace(body) == "" {
+		return "", nil, errors.New("No inputs provided, please provide a file with at least one device.")
+	}
+
+	var rows []Row
+	var err error
+	if mediaType == CSVMediaType {
+		rows, err = ReadCSV(body)
+	} else {
+		rows, err = ReadNDJSON(body)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(rows) == 0 {
+		return "", nil, errors.New("No inputs provided, please provide a file with at least one device.")
+	}
+
+	if len(rows) > MaxRows {
+		return "", nil, fmt.Errorf("Too many devices: A file can have at most %d devices.", MaxRows)
+	}
+
+	// Everything looks fine, return the mode and the rows.
+	return mode, rows, nil
+} // End of ValidateInputs function.
+
+// Reads a CSV file whose header row names the field of each column, i.e: id,deviceModel,name,note,serial.
+// Each row becomes a JSON object of its columns, so it's validated exactly like a device sent as JSON.
+func ReadCSV(body string) ([]Row, error) {
+	reader := csv.NewReader(strings.NewReader(body))
+	// Rows with a wrong number of values are reported on their own line, instead of failing the file.
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Wrong format: CSV header can not be read, %s.", err.Error())
+	}
+
+	seen := map[string]bool{}
+	for index, column := range header {
+		column = strings.TrimSpace(column)
+		if !Columns[column] {
+			return nil, errors.New("Wrong format: Unknown column " + column + " in CSV header, columns can be id, deviceModel, name, note and serial.")
+		}
+		if seen[column] {
+			return nil, errors.New("Wrong format: Column " + column + " is repeated in CSV header.")
+		}
+		seen[column] = true
+		header[index] = column
+	}
+
+	rows := []Row{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Wrong format: CSV can not be read, %s.", err.Error())
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(record) != len(header) {
+			rows = append(rows, Row{Line: line, Err: fmt.Errorf("Wrong format: Row has %d values, but CSV header has %d columns.", len(record), len(header))})
+			continue
+		}
+
+		object := map[string]string{}
+		for index, value := range record {
+			object[header[index]] = value
+		}
+		encoded, _ := json.Marshal(object)
+		rows = append(rows, Row{Line: line, JSON: string(encoded)})
+	}
+	return rows, nil
+} // End of ReadCSV function
+
+// Reads a file with a JSON object of a device on each line. Empty lines are skipped but still counted.
+func ReadNDJSON(body string) ([]Row, error) {
+	rows := []Row{}
+	for index, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rows = append(rows, Row{Line: index + 1, JSON: line})
+	}
+	return rows, nil
+} // End of ReadNDJSON function
+
+// Validates one row with the same rules as adding a single device.
+// The device is returned even when it's not valid, so its id can be reported.
+func ValidateDevice(row Row) (types.Device, error) {
+	if row.Err != nil {
+		return types.Device{}, row.Err
+	}
+
+	NewDevice, err := types.ParseDevice(row.JSON)
+	if err != nil {
+		return types.Device{}, err
+	}
+
+	// Ids are generated by the server, unless clients are allowed to choose them.
+	if NewDevice, err = ids.Assign(NewDevice); err != nil {
+		return NewDevice, err
+	}
+
+	if err = NewDevice.Validate(); err != nil {
+		return NewDevice, err
+	}
+
+	return NewDevice, nil
+} // End of ValidateDevice function.
+
+func main() {
+	lambda.Start(ImportDevices)
+}
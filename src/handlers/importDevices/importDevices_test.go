@@ -0,0 +1,268 @@
+package main
+
+import (
+	"clock"
+	"encoding/base64"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"ids"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+	ExpectedWrites     int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Number of devices the mock has written.
+	Writes int
+	// Devices as the mock has replaced them, by their ids.
+	Devices map[string]map[string]*dynamodb.AttributeValue
+}
+
+// Custom TransactWriteItems function for overriding the TransactWriteItems of devicemodels.Write for using in test scenarios.
+// The device is written like the mocked PutItem or UpdateItem does, its device model is checked and referenced in the
+// same transaction.
+func (self *MockDynamoDB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	items := input.TransactItems
+	if len(items) != 3 || items[1].ConditionCheck == nil || items[2].Put == nil {
+		return nil, awserr.New("ValidationException", "The device model is not guarded", nil)
+	}
+
+	var err error
+	if put := items[0].Put; put != nil {
+		_, err = self.PutItem(&dynamodb.PutItemInput{TableName: put.TableName, Item: put.Item, ConditionExpression: put.ConditionExpression})
+	} else {
+		update := items[0].Update
+		var output *dynamodb.UpdateItemOutput
+		output, err = self.UpdateItem(&dynamodb.UpdateItemInput{Key: update.Key, UpdateExpression: update.UpdateExpression, ExpressionAttributeNames: update.ExpressionAttributeNames})
+		if err == nil {
+			self.Devices[*update.Key["id"].S] = output.Attributes
+		}
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil, &dynamodb.TransactionCanceledException{CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")}, {Code: aws.String("None")},
+		}}
+	}
+	return new(dynamodb.TransactWriteItemsOutput), err
+}
+
+// Custom PutItem function for overriding the PutItem of importDevices.go for using in test scenarios.
+// "id_test", "legacy_test" and "deleted_test" already exist on the mocked DB.
+func (self *MockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	switch *input.Item["id"].S {
+	case "id_test", "legacy_test", "deleted_test":
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	self.Writes++
+	return new(dynamodb.PutItemOutput), nil
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of importDevices.go for using in test scenarios.
+// "id_test" exists on the mocked DB with version 3 and is active, added by bob, "legacy_test" has been added
+// before creators were stamped, and "deleted_test" has been deleted.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	// Replacing a device never makes the import its creator.
+	for _, name := range input.ExpressionAttributeNames {
+		if *name == "createdAt" || *name == "createdBy" {
+			return nil, awserr.New("ValidationException", "The creator of an existing device is written", nil)
+		}
+	}
+
+	attributes := map[string]*dynamodb.AttributeValue{
+		"id":          input.Key["id"],
+		"deviceModel": {S: aws.String("/devicemodels/testDeviceModel")},
+		"name":        {S: aws.String("testName")},
+		"note":        {S: aws.String("testNote")},
+		"serial":      {S: aws.String("testSerial")},
+		"status":      {S: aws.String("active")},
+		"updatedAt":   {S: aws.String("2020-01-02T03:04:05Z")},
+		"updatedBy":   {S: aws.String("anonymous")},
+		"version":     {N: aws.String("4")},
+	}
+	switch *input.Key["id"].S {
+	case "id_test":
+		attributes["createdAt"] = &dynamodb.AttributeValue{S: aws.String("2019-12-01T00:00:00Z")}
+		attributes["createdBy"] = &dynamodb.AttributeValue{S: aws.String("bob")}
+	case "legacy_test":
+	default:
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	self.Writes++
+	return &dynamodb.UpdateItemOutput{Attributes: attributes}, nil
+}
+
+// Custom GetItem function for overriding the GetItem of devicemodels.Check and devicemodels.Update for using in test scenarios.
+// Only "testDeviceModel" exists on the mocked DB, and devices which have been replaced.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	if device, ok := self.Devices[*input.Key["id"].S]; ok {
+		return &dynamodb.GetItemOutput{Item: device}, nil
+	}
+	if *input.Key["id"].S != "testDeviceModel" {
+		return new(dynamodb.GetItemOutput), nil
+	}
+	return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{"id": input.Key["id"]}}, nil
+}
+
+func Device(id string, version string) string {
+	return "{\"id\":\"" + id + "\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"" + version + "}"
+}
+
+// ReadCSV function in importDevices.go signature: input: (body string), output: ([]Row, error)
+func TestReadCSV(t *testing.T) {
+	// The note of the first row spans two lines, so the second row starts on line 4.
+	rows, err := ReadCSV("id,name\r\n1,\"Sensor,\nsecond line\"\r\n2,Gauge\n")
+	if err != nil || len(rows) != 2 || rows[0].Line != 2 || rows[1].Line != 4 || rows[0].JSON != "{\"id\":\"1\",\"name\":\"Sensor,\\nsecond line\"}" {
+		t.Errorf("** Reading CSV rows ** \n \t<resulted rows: %v> <resulted error: %v>", rows, err)
+	}
+} // End of TestReadCSV function
+
+// ImportDevices function in importDevices.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestImportDevices(t *testing.T) {
+	// Timestamps of the writes are known in advance.
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	os.Setenv("ALLOW_CLIENT_IDS", "true")
+	csvHeaders := map[string]string{"content-type": "text/csv; charset=utf-8"}
+	ndjsonHeaders := map[string]string{"Content-Type": "application/x-ndjson"}
+	header := "id,deviceModel,name,note,serial\n"
+	row := func(id string) string {
+		return id + ",/devicemodels/testDeviceModel,testName,testNote,testSerial\n"
+	}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Unsupported media type. **",
+			Request:            events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "application/json"}, Body: "[]"},
+			ExpectedBody:       "Unsupported media type: Content-Type must be text/csv or application/x-ndjson.",
+			ExpectedStatusCode: 415,
+		},
+
+		{
+			Name:               "** Testing: Unknown mode. **",
+			Request:            events.APIGatewayProxyRequest{Headers: csvHeaders, Body: header + row("1"), QueryStringParameters: map[string]string{"mode": "replace"}},
+			ExpectedBody:       "Wrong format: mode must be create-only, upsert or dry-run.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Empty file. **",
+			Request:            events.APIGatewayProxyRequest{Headers: ndjsonHeaders, Body: "\n\n"},
+			ExpectedBody:       "No inputs provided, please provide a file with at least one device.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Only a CSV header. **",
+			Request:            events.APIGatewayProxyRequest{Headers: csvHeaders, Body: header},
+			ExpectedBody:       "No inputs provided, please provide a file with at least one device.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Unknown CSV column. **",
+			Request:            events.APIGatewayProxyRequest{Headers: csvHeaders, Body: "id,version\n1,2\n"},
+			ExpectedBody:       "Wrong format: Unknown column version in CSV header, columns can be id, deviceModel, name, note and serial.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Repeated CSV column. **",
+			Request:            events.APIGatewayProxyRequest{Headers: csvHeaders, Body: "id,name,id\n1,2,3\n"},
+			ExpectedBody:       "Wrong format: Column id is repeated in CSV header.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Too many rows. **",
+			Request:            events.APIGatewayProxyRequest{Headers: csvHeaders, Body: header + strings.Repeat(row("1"), MaxRows+1)},
+			ExpectedBody:       "Too many devices: A file can have at most 500 devices.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:    "** Testing: Create-only CSV with a byte order mark. **",
+			Request: events.APIGatewayProxyRequest{Headers: csvHeaders, Body: "\ufeff" + header + row("1") + "2,/devicemodels/testDeviceModel,testName\n" + row("id_test") + row("1") + "3,/devicemodels/typo,testName,testNote,testSerial\n" + row(",")},
+			ExpectedBody: "{\"mode\":\"create-only\",\"results\":[" +
+				"{\"line\":2,\"id\":\"1\",\"status\":201,\"device\":" + Device("1", ",\"status\":\"provisioning\",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":1") + "}," +
+				"{\"line\":3,\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Wrong format: Row has 3 values, but CSV header has 5 columns.\"}}," +
+				"{\"line\":4,\"id\":\"id_test\",\"status\":409,\"error\":{\"code\":\"DeviceAlreadyExists\",\"message\":\"A device with id id_test already exists, use mode=upsert to replace it.\"}}," +
+				"{\"line\":5,\"id\":\"1\",\"status\":409,\"error\":{\"code\":\"DuplicateId\",\"message\":\"The id 1 is used by an earlier row on line 2.\"}}," +
+				"{\"line\":6,\"id\":\"3\",\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Unknown device model: /devicemodels/typo\"}}," +
+				"{\"line\":7,\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Wrong format: Row has 6 values, but CSV header has 5 columns.\"}}]}",
+			ExpectedStatusCode: 207,
+			ExpectedWrites:     1,
+		},
+
+		{
+			Name:    "** Testing: Upsert NDJSON. **",
+			Request: events.APIGatewayProxyRequest{Headers: ndjsonHeaders, QueryStringParameters: map[string]string{"mode": "upsert"}, Body: Device("1", "") + "\n\n" + Device("id_test", "") + "\n" + Device("legacy_test", "") + "\n" + Device("deleted_test", "") + "\n{\"id\":\"4\"}\n[]\n" + Device("6", ",\"tags\":{\"env\":\"lab one\"}")},
+			ExpectedBody: "{\"mode\":\"upsert\",\"results\":[" +
+				"{\"line\":1,\"id\":\"1\",\"status\":201,\"device\":" + Device("1", ",\"status\":\"provisioning\",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":1") + "}," +
+				"{\"line\":3,\"id\":\"id_test\",\"status\":200,\"device\":" + Device("id_test", ",\"status\":\"active\",\"createdAt\":\"2019-12-01T00:00:00Z\",\"createdBy\":\"bob\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":4") + "}," +
+				"{\"line\":4,\"id\":\"legacy_test\",\"status\":200,\"device\":" + Device("legacy_test", ",\"status\":\"active\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":4") + "}," +
+				"{\"line\":5,\"id\":\"deleted_test\",\"status\":409,\"error\":{\"code\":\"DeviceDeleted\",\"message\":\"The device deleted_test has been deleted, restore it before importing it again.\"}}," +
+				"{\"line\":6,\"id\":\"4\",\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Missing field: Device Model\"}}," +
+				"{\"line\":7,\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Wrong format: Inputs must be a valid JSON.\"}}," +
+				"{\"line\":8,\"id\":\"6\",\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Wrong format: value of tag env must be at most 63 letters, digits, '-', '_' or '.', starting and ending with a letter or a digit.\"}}]}",
+			ExpectedStatusCode: 207,
+			ExpectedWrites:     3,
+		},
+
+		{
+			Name:    "** Testing: Dry-run of a base64 encoded CSV. **",
+			Request: events.APIGatewayProxyRequest{Headers: csvHeaders, QueryStringParameters: map[string]string{"mode": "dry-run"}, IsBase64Encoded: true, Body: base64.StdEncoding.EncodeToString([]byte(header + row("id_test") + "5,,testName,testNote,testSerial\n"))},
+			ExpectedBody: "{\"mode\":\"dry-run\",\"results\":[" +
+				"{\"line\":2,\"id\":\"id_test\",\"status\":200,\"device\":" + Device("id_test", ",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\"") + "}," +
+				"{\"line\":3,\"id\":\"5\",\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Missing field: Device Model\"}}]}",
+			ExpectedStatusCode: 207,
+		},
+	}
+
+	for _, test := range testCases {
+		mock := &MockDynamoDB{Devices: map[string]map[string]*dynamodb.AttributeValue{}}
+		TestAws = &AmazonWebServices{DynamoDB: mock}
+
+		// Executing each test cases scenario.
+		response, _ := ImportDevices(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody || mock.Writes != test.ExpectedWrites {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> <expected writes: %d> <resulted writes: %d> \n \t<expected body: %s> \n \t<resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedWrites, mock.Writes, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestImportDevices function
+
+// ImportDevices function without client ids, rows without id are added with generated ones.
+func TestImportDevicesGeneratedIds(t *testing.T) {
+	// Timestamps and random parts of the ids are known in advance.
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	ids.Entropy = func(random []byte) (int, error) {
+		for index := range random {
+			random[index] = 0
+		}
+		return len(random), nil
+	}
+	os.Setenv("ALLOW_CLIENT_IDS", "")
+	mock := &MockDynamoDB{Devices: map[string]map[string]*dynamodb.AttributeValue{}}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+
+	body := "id,deviceModel,name,note,serial\n,/devicemodels/testDeviceModel,testName,testNote,testSerial\nid_test,/devicemodels/testDeviceModel,testName,testNote,testSerial\n"
+	response, _ := ImportDevices(events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "text/csv"}, QueryStringParameters: map[string]string{"mode": "upsert"}, Body: body})
+	expected := "{\"mode\":\"upsert\",\"results\":[" +
+		"{\"line\":2,\"id\":\"01DXJ3BK480000000000000000\",\"status\":201,\"device\":" + Device("01DXJ3BK480000000000000000", ",\"status\":\"provisioning\",\"createdAt\":\"2020-01-02T03:04:05Z\",\"createdBy\":\"anonymous\",\"updatedAt\":\"2020-01-02T03:04:05Z\",\"updatedBy\":\"anonymous\",\"version\":1") + "}," +
+		"{\"line\":3,\"id\":\"id_test\",\"status\":400,\"error\":{\"code\":\"InvalidDevice\",\"message\":\"Wrong id: Ids are given by the server, please leave id out of the device.\"}}]}"
+	if response.StatusCode != 207 || response.Body != expected || mock.Writes != 1 {
+		t.Errorf("** Testing: Generated ids. ** \n \t<resulted writes: %d> \n \t<expected body: %s> \n \t<resulted body: %s>", mock.Writes, expected, response.Body)
+	}
+} // End of TestImportDevicesGeneratedIds function
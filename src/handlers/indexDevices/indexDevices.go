@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"search"
+	"sort"
+	"strconv"
+	"tags"
+	"time"
+	"types"
+)
+
+// Most requests DynamoDB accepts in one BatchWriteItem request.
+const WriteChunkSize = 25
+
+// Number of times unprocessed requests are sent again, before the stream batch fails and is retried by Lambda.
+const MaxAttempts = 5
+
+// Waiting time before the first retry, doubled on every next one.
+const BaseBackoff = 50 * time.Millisecond
+
+// Replaced in tests, so retries do not slow them down.
+var Sleep = time.Sleep
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's BatchWriteItem function inside, on an index table, in chunks DynamoDB accepts.
+// Unprocessed requests are sent again with exponential backoff.
+func (self *AmazonWebServices) BatchWrite(tableName string, requests []*dynamodb.WriteRequest) error {
+	for start := 0; start < len(requests); start += WriteChunkSize {
+		end := start + WriteChunkSize
+		if end > len(requests) {
+			end = len(requests)
+		}
+
+		requestItems := map[string][]*dynamodb.WriteRequest{tableName: requests[start:end]}
+		for attempt := 1; len(requestItems[tableName]) != 0; attempt++ {
+			if attempt > MaxAttempts {
+				return errors.New("Requests are still unprocessed after retrying.")
+			}
+			if attempt > 1 {
+				Sleep(BaseBackoff << uint(attempt-2))
+			}
+
+			// Calling either BatchWriteItem function of interface, defined in indexDevices_test.go file, or api with the input we've provided.
+			result, err := self.DynamoDB.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: requestItems})
+			if err != nil {
+				return err
+			}
+			requestItems = result.UnprocessedItems
+		}
+	}
+
+	return nil
+}
+
+// The handler function which will be first started from main function, with the changes of the devices table.
+// Both the search index and the tag index are kept up to date.
+// Records of a device arrive in the order they were written. On an error Lambda retries the whole batch,
+// which is safe as applying the same change twice leaves the index as it is.
+func IndexDevices(event events.DynamoDBEvent) error {
+	for _, record := range event.Records {
+		old := DeviceFromImage(record.Change.OldImage)
+		new := DeviceFromImage(record.Change.NewImage)
+
+		// Get desire tables' names from OS's environmental varible.
+		err := TestAws.BatchWrite(os.Getenv("SEARCH_INDEX_TABLE_NAME"), Changes(old, new))
+		if err == nil {
+			err = TestAws.BatchWrite(os.Getenv("TAG_INDEX_TABLE_NAME"), TagChanges(old, new))
+		}
+		if err != nil {
+			// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+			fmt.Println(fmt.Sprintf("Failed to index device %s: %s", record.Change.Keys["id"].String(), err.Error()))
+			return err
+		}
+	}
+	return nil
+} // End of IndexDevices function
+
+// Only the fields which are searched or indexed, or tell if the device has been deleted, are read from a stream image.
+// An INSERT has no old image and a REMOVE has no new one, both are read as a device without an id.
+func DeviceFromImage(image map[string]events.DynamoDBAttributeValue) types.Device {
+	field := func(name string) string {
+		if value, ok := image[name]; ok && value.DataType() == events.DataTypeString {
+			return value.String()
+		}
+		return ""
+	}
+
+	device := types.Device{
+		ID:        field("id"),
+		Name:      field("name"),
+		Note:      field("note"),
+		DeletedAt: field("deletedAt"),
+	}
+	if value, ok := image["tags"]; ok && value.DataType() == events.DataTypeMap {
+		device.Tags = map[string]string{}
+		for key, tag := range value.Map() {
+			if tag.DataType() == events.DataTypeString {
+				device.Tags[key] = tag.String()
+			}
+		}
+	}
+	return device
+} // End of DeviceFromImage function
+
+// Changes returns the writes which turn the index entries of the old device into the ones of the new device.
+// Terms which have not changed, or kept their weight, are not written again.
+func Changes(old types.Device, new types.Device) []*dynamodb.WriteRequest {
+	oldTerms, newTerms := search.DeviceTerms(old), search.DeviceTerms(new)
+	requests := []*dynamodb.WriteRequest{}
+
+	for _, term := range SortedTerms(oldTerms) {
+		if _, ok := newTerms[term]; !ok {
+			requests = append(requests, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{
+				Key: map[string]*dynamodb.AttributeValue{
+					"term": {S: aws.String(term)},
+					"id":   {S: aws.String(old.ID)},
+				},
+			}})
+		}
+	}
+
+	for _, term := range SortedTerms(newTerms) {
+		if weight, ok := oldTerms[term]; !ok || weight != newTerms[term] {
+			requests = append(requests, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{
+				Item: map[string]*dynamodb.AttributeValue{
+					"term":   {S: aws.String(term)},
+					"id":     {S: aws.String(new.ID)},
+					"weight": {N: aws.String(strconv.FormatInt(newTerms[term], 10))},
+				},
+			}})
+		}
+	}
+
+	return requests
+} // End of Changes function
+
+// TagChanges returns the writes which turn the tag index entries of the old device into the ones of the new device.
+// Deleted devices have no entries, so they are not found by their tags until they are restored.
+func TagChanges(old types.Device, new types.Device) []*dynamodb.WriteRequest {
+	oldEntries, newEntries := map[string]bool{}, map[string]bool{}
+	if old.ID != "" && old.DeletedAt == "" {
+		for _, entry := range tags.IndexEntries(old.Tags) {
+			oldEntries[entry] = true
+		}
+	}
+	if new.ID != "" && new.DeletedAt == "" {
+		for _, entry := range tags.IndexEntries(new.Tags) {
+			newEntries[entry] = true
+		}
+	}
+
+	requests := []*dynamodb.WriteRequest{}
+	for _, entry := range tags.IndexEntries(old.Tags) {
+		if oldEntries[entry] && !newEntries[entry] {
+			requests = append(requests, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{
+				Key: map[string]*dynamodb.AttributeValue{
+					"tag": {S: aws.String(entry)},
+					"id":  {S: aws.String(old.ID)},
+				},
+			}})
+		}
+	}
+	for _, entry := range tags.IndexEntries(new.Tags) {
+		if newEntries[entry] && !oldEntries[entry] {
+			requests = append(requests, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{
+				Item: map[string]*dynamodb.AttributeValue{
+					"tag": {S: aws.String(entry)},
+					"id":  {S: aws.String(new.ID)},
+				},
+			}})
+		}
+	}
+
+	return requests
+} // End of TagChanges function
+
+// Terms are written in a stable order, which keeps the logs and the tests readable.
+func SortedTerms(terms map[string]int64) []string {
+	sorted := []string{}
+	for term := range terms {
+		sorted = append(sorted, term)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func main() {
+	lambda.Start(IndexDevices)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"testing"
+	"time"
+	"types"
+)
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Index entries on the mocked DB, i.e: "sensor id_test" is 6.
+	Entries map[string]string
+	// Tag index entries on the mocked DB, i.e: "site=berlin id_test".
+	Tags map[string]bool
+	// Number of calls the mock leaves the last request unprocessed.
+	Throttles int
+}
+
+// Custom BatchWriteItem function for overriding the BatchWriteItem of indexDevices.go for using in test scenarios.
+func (self *MockDynamoDB) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	output := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]*dynamodb.WriteRequest{}}
+	for table, requests := range input.RequestItems {
+		if self.Throttles > 0 {
+			self.Throttles--
+			output.UnprocessedItems[table] = requests[len(requests)-1:]
+			requests = requests[:len(requests)-1]
+		}
+		for _, request := range requests {
+			switch {
+			case request.PutRequest != nil && request.PutRequest.Item["tag"] != nil:
+				item := request.PutRequest.Item
+				self.Tags[*item["tag"].S+" "+*item["id"].S] = true
+			case request.PutRequest != nil:
+				item := request.PutRequest.Item
+				self.Entries[*item["term"].S+" "+*item["id"].S] = *item["weight"].N
+			case request.DeleteRequest.Key["tag"] != nil:
+				key := request.DeleteRequest.Key
+				delete(self.Tags, *key["tag"].S+" "+*key["id"].S)
+			default:
+				key := request.DeleteRequest.Key
+				delete(self.Entries, *key["term"].S+" "+*key["id"].S)
+			}
+		}
+	}
+	return output, nil
+}
+
+func Image(device types.Device) map[string]events.DynamoDBAttributeValue {
+	image := map[string]events.DynamoDBAttributeValue{
+		"id":      events.NewStringAttribute(device.ID),
+		"name":    events.NewStringAttribute(device.Name),
+		"note":    events.NewStringAttribute(device.Note),
+		"version": events.NewNumberAttribute("2"),
+	}
+	if device.DeletedAt != "" {
+		image["deletedAt"] = events.NewStringAttribute(device.DeletedAt)
+	}
+	if device.Tags != nil {
+		tags := map[string]events.DynamoDBAttributeValue{}
+		for key, value := range device.Tags {
+			tags[key] = events.NewStringAttribute(value)
+		}
+		image["tags"] = events.NewMapAttribute(tags)
+	}
+	return image
+}
+
+func Record(eventName string, old *types.Device, new *types.Device) events.DynamoDBEventRecord {
+	record := events.DynamoDBEventRecord{EventName: eventName}
+	record.Change.Keys = map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute("id_test")}
+	if old != nil {
+		record.Change.OldImage = Image(*old)
+	}
+	if new != nil {
+		record.Change.NewImage = Image(*new)
+	}
+	return record
+}
+
+// Entries of the mocked DB as sorted "term id weight" lines.
+func (self *MockDynamoDB) String() string {
+	lines := []string{}
+	for _, entry := range SortedEntries(self.Entries) {
+		lines = append(lines, entry+" "+self.Entries[entry])
+	}
+	return strings.Join(lines, ", ")
+}
+
+// Tag index entries of the mocked DB as sorted "tag id" lines.
+func (self *MockDynamoDB) TagString() string {
+	entries := map[string]string{}
+	for entry := range self.Tags {
+		entries[entry] = ""
+	}
+	return strings.Join(SortedEntries(entries), ", ")
+}
+
+func SortedEntries(entries map[string]string) []string {
+	terms := map[string]int64{}
+	for entry := range entries {
+		terms[entry] = 0
+	}
+	return SortedTerms(terms)
+}
+
+// Changes function in indexDevices.go signature: input: (old types.Device, new types.Device), output: ([]*dynamodb.WriteRequest)
+func TestChanges(t *testing.T) {
+	old := types.Device{ID: "id_test", Name: "Sensor", Note: "Old"}
+	new := types.Device{ID: "id_test", Name: "Sensor", Note: "New"}
+
+	requests := Changes(old, new)
+	summary := []string{}
+	for _, request := range requests {
+		if request.PutRequest != nil {
+			summary = append(summary, "put "+*request.PutRequest.Item["term"].S)
+		} else {
+			summary = append(summary, "delete "+*request.DeleteRequest.Key["term"].S)
+		}
+	}
+	// Terms of the unchanged name are not written again.
+	if strings.Join(summary, ", ") != "delete old, put new" {
+		t.Errorf("** Changing the note ** \n \t<expected requests: delete old, put new> <resulted requests: %s>", strings.Join(summary, ", "))
+	}
+
+	if len(Changes(old, old)) != 0 {
+		t.Errorf("** Writing the same device again ** \n \t<expected requests: none> <resulted requests: %d>", len(Changes(old, old)))
+	}
+} // End of TestChanges function
+
+// TagChanges function in indexDevices.go signature: input: (old types.Device, new types.Device), output: ([]*dynamodb.WriteRequest)
+func TestTagChanges(t *testing.T) {
+	old := types.Device{ID: "id_test", Tags: map[string]string{"site": "berlin", "env": "lab"}}
+	new := types.Device{ID: "id_test", Tags: map[string]string{"site": "paris", "env": "lab"}}
+
+	summary := []string{}
+	for _, request := range TagChanges(old, new) {
+		if request.PutRequest != nil {
+			summary = append(summary, "put "+*request.PutRequest.Item["tag"].S)
+		} else {
+			summary = append(summary, "delete "+*request.DeleteRequest.Key["tag"].S)
+		}
+	}
+	// The key alone is still there, only the entry of the value changes.
+	if strings.Join(summary, ", ") != "delete site=berlin, put site=paris" {
+		t.Errorf("** Changing a tag ** \n \t<expected requests: delete site=berlin, put site=paris> <resulted requests: %s>", strings.Join(summary, ", "))
+	}
+
+	deleted := new
+	deleted.DeletedAt = "2020-01-01T00:00:00Z"
+	if len(TagChanges(new, deleted)) != 4 {
+		t.Errorf("** Deleting a tagged device ** \n \t<expected requests: 4> <resulted requests: %d>", len(TagChanges(new, deleted)))
+	}
+} // End of TestTagChanges function
+
+// IndexDevices function in indexDevices.go signature: input: (event events.DynamoDBEvent), output: (error)
+func TestIndexDevices(t *testing.T) {
+	Sleep = func(time.Duration) {}
+	mock := &MockDynamoDB{Entries: map[string]string{}, Tags: map[string]bool{}}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+
+	added := types.Device{ID: "id_test", Name: "Sensor", Note: "Testing it", Tags: map[string]string{"site": "berlin"}}
+	renamed := types.Device{ID: "id_test", Name: "Gauge", Note: "Testing it", Tags: map[string]string{"site": "berlin"}}
+	deleted := renamed
+	deleted.DeletedAt = "2020-01-01T00:00:00Z"
+
+	testCases := []struct {
+		Name            string
+		Record          events.DynamoDBEventRecord
+		ExpectedEntries string
+		ExpectedTags    string
+	}{
+		{
+			Name:            "** Testing: Adding a device. **",
+			Record:          Record("INSERT", nil, &added),
+			ExpectedEntries: "it id_test 2, sen id_test 3, sens id_test 3, senso id_test 3, sensor id_test 6, tes id_test 1, test id_test 2, testi id_test 1, testin id_test 1, testing id_test 1",
+			ExpectedTags:    "site id_test, site=berlin id_test",
+		},
+
+		{
+			Name:            "** Testing: Renaming the device. **",
+			Record:          Record("MODIFY", &added, &renamed),
+			ExpectedEntries: "gau id_test 3, gaug id_test 3, gauge id_test 6, it id_test 2, tes id_test 1, test id_test 2, testi id_test 1, testin id_test 1, testing id_test 1",
+			ExpectedTags:    "site id_test, site=berlin id_test",
+		},
+
+		{
+			Name:            "** Testing: Deleting the device. **",
+			Record:          Record("MODIFY", &renamed, &deleted),
+			ExpectedEntries: "",
+		},
+
+		{
+			Name:            "** Testing: Restoring the device. **",
+			Record:          Record("MODIFY", &deleted, &added),
+			ExpectedEntries: "it id_test 2, sen id_test 3, sens id_test 3, senso id_test 3, sensor id_test 6, tes id_test 1, test id_test 2, testi id_test 1, testin id_test 1, testing id_test 1",
+			ExpectedTags:    "site id_test, site=berlin id_test",
+		},
+
+		{
+			Name:            "** Testing: Purging the device. **",
+			Record:          Record("REMOVE", &added, nil),
+			ExpectedEntries: "",
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		err := IndexDevices(events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{test.Record}})
+		if err != nil || mock.String() != test.ExpectedEntries || mock.TagString() != test.ExpectedTags {
+			t.Errorf("%s \n \t<expected entries: %s> \n \t<resulted entries: %s> \n \t<expected tags: %s> <resulted tags: %s> <resulted error: %v>", test.Name, test.ExpectedEntries, mock.String(), test.ExpectedTags, mock.TagString(), err)
+		}
+	}
+
+	// Index entries which are still unprocessed after retrying fail the batch, so Lambda retries it.
+	mock = &MockDynamoDB{Entries: map[string]string{}, Tags: map[string]bool{}, Throttles: MaxAttempts}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+	err := IndexDevices(events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{Record("INSERT", nil, &added)}})
+	if err == nil {
+		t.Errorf("** Testing: Unprocessed entries. ** \n \t<expected error> <resulted entries: %s>", fmt.Sprint(mock.Entries))
+	}
+} // End of TestIndexDevices function
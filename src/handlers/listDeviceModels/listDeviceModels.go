@@ -0,0 +1,170 @@
+package main
+
+import (
+	"cursor"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"strconv"
+	"types"
+)
+
+// Number of device models returned when client does not ask for a specific page size.
+const DefaultLimit = 25
+
+// Server enforced maximum page size, bigger limits will be lowered to it.
+const MaxLimit = 100
+
+// Cursors of this endpoint can not be used on other list endpoints.
+const CursorScope = "devicemodels"
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's Scan function inside.
+func (self *AmazonWebServices) List(limit int64, startKey map[string]*dynamodb.AttributeValue) (*dynamodb.ScanOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICE_MODELS_TABLE_NAME"))
+
+	var input = &dynamodb.ScanInput{
+		TableName: tableName,
+		Limit:     aws.Int64(limit),
+	}
+	// Continue right after the last item of the previous page.
+	if len(startKey) != 0 {
+		input.ExclusiveStartKey = startKey
+	}
+
+	// Calling either Scan function of interface, defined in listDeviceModels_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Scan(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func ListDeviceModels(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// First & foremost we have to validate user input.
+	limit, startKey, err := ValidateInputs(request)
+	// A missing cursor secret is a misconfiguration of the server, not a mistake of the client.
+	if err == cursor.ErrMissingSecret {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}, nil
+	}
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	result, err := TestAws.List(limit, startKey)
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Checking the result of the DynamoDB scan.
+	return ValidateDatabaseResult(result), nil
+} // End of ListDeviceModels function
+
+func ValidateInputs(request events.APIGatewayProxyRequest) (int64, map[string]*dynamodb.AttributeValue, error) {
+	var limit int64 = DefaultLimit
+
+	if value, ok := request.QueryStringParameters["limit"]; ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			return 0, nil, errors.New("Wrong format: limit must be a positive number.")
+		}
+		limit = parsed
+	}
+
+	// Bigger pages than the maximum are not an error, they will be shortened.
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	token := request.QueryStringParameters["cursor"]
+	if token == "" {
+		return limit, nil, nil
+	}
+
+	startKey, err := cursor.Decode(CursorScope, token)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Everything looks fine, return page size and where the page starts.
+	return limit, startKey, nil
+} // End of ValidateInputs function.
+
+func ValidateDatabaseResult(result *dynamodb.ScanOutput) events.APIGatewayProxyResponse {
+	page := types.DeviceModelPage{DeviceModels: []types.DeviceModel{}}
+
+	// Deserialization/Decoding "result.Items" to Go structs.
+	err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &page.DeviceModels)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}
+	}
+
+	// No LastEvaluatedKey means the last page has been reached and the cursor stays empty.
+	page.NextCursor, err = cursor.Encode(CursorScope, result.LastEvaluatedKey)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}
+	}
+
+	// Serialization/Encoding page to JSON.
+	jsonResponse, _ := json.Marshal(page)
+
+	// Return founded page as JSON type with 200 HTTP status code.
+	return events.APIGatewayProxyResponse{
+		Body:       string(jsonResponse),
+		StatusCode: 200,
+	}
+} // End of ValidateDatabaseResult function
+
+func main() {
+	lambda.Start(ListDeviceModels)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"cursor"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+// Custom Scan function for overriding the Scan of listDeviceModels.go for using in test scenarios.
+// Mocking Scan output to a page with one device model, followed by a last page without any.
+func (self *MockDynamoDB) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	mockOutput := new(dynamodb.ScanOutput)
+
+	if input.ExclusiveStartKey == nil {
+		mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{{
+			"id":           &dynamodb.AttributeValue{S: aws.String("model_test")},
+			"manufacturer": &dynamodb.AttributeValue{S: aws.String("manufacturer_test")},
+			"modelName":    &dynamodb.AttributeValue{S: aws.String("modelName_test")},
+		}})
+		mockOutput.SetLastEvaluatedKey(map[string]*dynamodb.AttributeValue{"id": {S: aws.String("model_test")}})
+	}
+	return mockOutput, nil
+}
+
+// ListDeviceModels function in listDeviceModels.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestListDeviceModels(t *testing.T) {
+	os.Setenv("CURSOR_SECRET", "secret_test")
+	nextCursor, _ := cursor.Encode(CursorScope, map[string]*dynamodb.AttributeValue{"id": {S: aws.String("model_test")}})
+	devicesCursor, _ := cursor.Encode("devices", map[string]*dynamodb.AttributeValue{"id": {S: aws.String("model_test")}})
+
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Wrong limit. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"limit": "0"}},
+			ExpectedBody:       "Wrong format: limit must be a positive number.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Cursor of the devices list. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"cursor": devicesCursor}},
+			ExpectedBody:       "Invalid cursor.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: First page. **",
+			Request:            events.APIGatewayProxyRequest{},
+			ExpectedBody:       "{\"deviceModels\":[{\"id\":\"model_test\",\"manufacturer\":\"manufacturer_test\",\"modelName\":\"modelName_test\"}],\"nextCursor\":\"" + nextCursor + "\"}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Last page. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"cursor": nextCursor}},
+			ExpectedBody:       "{\"deviceModels\":[]}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := ListDeviceModels(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// Without a secret cursors can not be checked, which is an error of the server.
+	os.Unsetenv("CURSOR_SECRET")
+	defer os.Setenv("CURSOR_SECRET", "secret_test")
+	response, _ := ListDeviceModels(events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"cursor": nextCursor}})
+	if response.StatusCode != 500 || response.Body != "Internal Server Error." {
+		t.Errorf("** Testing: Missing cursor secret. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+} // End of TestListDeviceModels function
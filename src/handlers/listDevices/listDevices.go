@@ -0,0 +1,710 @@
+package main
+
+import (
+	"cursor"
+	"encoding/json"
+	"errors"
+	"fields"
+	"filter"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"tags"
+	"time"
+	"types"
+)
+
+// Number of devices returned when client does not ask for a specific page size.
+const DefaultLimit = 25
+
+// Server enforced maximum page size, bigger limits will be lowered to it.
+const MaxLimit = 100
+
+// Cursors of this endpoint can not be used on other list endpoints.
+const CursorScope = "devices"
+
+// Global secondary index of the devices table on the normalized serial.
+const SerialIndex = "serialKey-index"
+
+// Global secondary index of the devices table on the device model, sorted by name.
+const DeviceModelIndex = "deviceModel-name-index"
+
+// Devices read by each request, when all devices of a listing are read for sorting them.
+const SortReadLimit = 1000
+
+// Number of times unprocessed keys are read again, before the request fails.
+const MaxAttempts = 5
+
+// Waiting time before the first retry, doubled on every next one.
+const BaseBackoff = 50 * time.Millisecond
+
+// Replaced in tests, so retries do not slow them down.
+var Sleep = time.Sleep
+
+// Fields which filters can compare.
+var FilterFields = map[string]bool{
+	"id":          true,
+	"deviceModel": true,
+	"name":        true,
+	"note":        true,
+	"serial":      true,
+	"status":      true,
+	"createdAt":   true,
+	"createdBy":   true,
+	"updatedAt":   true,
+	"updatedBy":   true,
+}
+
+// Sort orders of the devices, by name ascending or descending.
+const (
+	SortByName     = "name"
+	SortByNameDesc = "-name"
+)
+
+// Serial lookups match the serial as it's stored, or ignore its case and whitespaces.
+const (
+	ExactMatch      = "exact"
+	NormalizedMatch = "normalized"
+)
+
+// Struct containing what a list request asks for, after validation.
+type ListQuery struct {
+	Limit    int64
+	StartKey map[string]*dynamodb.AttributeValue
+	// Empty when all devices are listed.
+	Serial string
+	Match  string
+	// Filter as the client has sent it, and the parts of it which are not used as key conditions.
+	FilterText string
+	Filter     filter.Node
+	Sort       string
+	// Set when the filter asks for a single device model, then its index is read instead of scanning the table.
+	DeviceModel string
+	NameKey     *filter.Comparison
+	// Fields which client has asked for, all of them when empty.
+	Fields []string
+	// Tag selector as the client has sent it, then the tag index is read instead of scanning the table.
+	TagsText string
+	Tags     tags.Selector
+}
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's Scan function inside.
+func (self *AmazonWebServices) List(query ListQuery) (*dynamodb.ScanOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	expr, err := fields.Project(expression.NewBuilder().WithFilter(FilterCondition(query.Filter)), query.Fields).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	// Deleted devices are skipped. Limit is applied before filtering, so a page may have less devices than limit.
+	var input = &dynamodb.ScanInput{
+		TableName:                 tableName,
+		Limit:                     aws.Int64(query.Limit),
+		FilterExpression:          expr.Filter(),
+		ProjectionExpression:      expr.Projection(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+	// Continue right after the last item of the previous page.
+	if len(query.StartKey) != 0 {
+		input.ExclusiveStartKey = query.StartKey
+	}
+
+	// Calling either Scan function of interface, defined in listDevices_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Scan(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's Query function inside, on the serial index.
+// Every device with the same normalized serial is found. Exact match also needs the stored serial to be the same.
+func (self *AmazonWebServices) QueryBySerial(query ListQuery) (*dynamodb.QueryOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	keyCondition := expression.Key("serialKey").Equal(expression.Value(types.NormalizeSerial(query.Serial)))
+	condition := FilterCondition(query.Filter)
+	if query.Match == ExactMatch {
+		condition = condition.And(expression.Name("serial").Equal(expression.Value(query.Serial)))
+	}
+
+	expr, err := fields.Project(expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(condition), query.Fields).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.QueryInput{
+		TableName:                 tableName,
+		IndexName:                 aws.String(SerialIndex),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ProjectionExpression:      expr.Projection(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		Limit:                     aws.Int64(query.Limit),
+	}
+	// Continue right after the last item of the previous page.
+	if len(query.StartKey) != 0 {
+		input.ExclusiveStartKey = query.StartKey
+	}
+
+	// Calling either Query function of interface, defined in listDevices_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Query(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's Query function inside, on the device model index.
+// The index is sorted by name, so a name comparison of the filter can be a key condition as well.
+func (self *AmazonWebServices) QueryByDeviceModel(query ListQuery) (*dynamodb.QueryOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	keyCondition := expression.Key("deviceModel").Equal(expression.Value(query.DeviceModel))
+	if query.NameKey != nil {
+		keyCondition = keyCondition.And(query.NameKey.KeyCondition())
+	}
+
+	expr, err := fields.Project(expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(FilterCondition(query.Filter)), query.Fields).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.QueryInput{
+		TableName:                 tableName,
+		IndexName:                 aws.String(DeviceModelIndex),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ProjectionExpression:      expr.Projection(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(query.Sort != SortByNameDesc),
+		Limit:                     aws.Int64(query.Limit),
+	}
+	// Continue right after the last item of the previous page.
+	if len(query.StartKey) != 0 {
+		input.ExclusiveStartKey = query.StartKey
+	}
+
+	// Calling either Query function of interface, defined in listDevices_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Query(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's Query function inside, on the tag index table, for the ids of the
+// devices which have the tags of the indexed requirement of the selector. A set of values is a query for each value.
+// Ids are returned in order, and the key of the last one when there may be more of them.
+func (self *AmazonWebServices) QueryByTags(query ListQuery) ([]string, map[string]*dynamodb.AttributeValue, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("TAG_INDEX_TABLE_NAME"))
+
+	after := ""
+	if value, ok := query.StartKey["id"]; ok && value.S != nil {
+		after = *value.S
+	}
+
+	requirement, _ := query.Tags.Indexed()
+	found := map[string]bool{}
+	more := false
+	for _, entry := range requirement.Entries() {
+		keyCondition := expression.Key("tag").Equal(expression.Value(entry))
+		if after != "" {
+			keyCondition = keyCondition.And(expression.Key("id").GreaterThan(expression.Value(after)))
+		}
+		expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var input = &dynamodb.QueryInput{
+			TableName:                 tableName,
+			KeyConditionExpression:    expr.KeyCondition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			Limit:                     aws.Int64(query.Limit),
+		}
+
+		// Calling either Query function of interface, defined in listDevices_test.go file, or api with the input we've provided.
+		result, err := self.DynamoDB.Query(input)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, item := range result.Items {
+			if value, ok := item["id"]; ok && value.S != nil {
+				found[*value.S] = true
+			}
+		}
+		more = more || len(result.LastEvaluatedKey) != 0
+	}
+
+	// Each value has returned its first ids, so the first ones of all of them are the first ids of the set.
+	ids := []string{}
+	for id := range found {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if int64(len(ids)) > query.Limit {
+		ids, more = ids[:query.Limit], true
+	}
+
+	if !more || len(ids) == 0 {
+		return ids, nil, nil
+	}
+	return ids, map[string]*dynamodb.AttributeValue{"id": {S: aws.String(ids[len(ids)-1])}}, nil
+}
+
+// Preparing DynamoDB Session and Calling DB's BatchGetItem function inside, for the devices found on the tag index.
+// Unprocessed keys are sent again with exponential backoff. Items are returned in no particular order.
+func (self *AmazonWebServices) BatchGet(ids []string) ([]map[string]*dynamodb.AttributeValue, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := os.Getenv("DEVICES_TABLE_NAME")
+	items := []map[string]*dynamodb.AttributeValue{}
+	if len(ids) == 0 {
+		return items, nil
+	}
+
+	// There are never more ids than MaxLimit, which DynamoDB accepts in one request.
+	keys := []map[string]*dynamodb.AttributeValue{}
+	for _, id := range ids {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}})
+	}
+	requestItems := map[string]*dynamodb.KeysAndAttributes{tableName: {Keys: keys}}
+
+	for attempt := 1; len(requestItems) != 0; attempt++ {
+		if attempt > MaxAttempts {
+			return nil, errors.New("Keys are still unprocessed after retrying.")
+		}
+		if attempt > 1 {
+			Sleep(BaseBackoff << uint(attempt-2))
+		}
+
+		// Calling either BatchGetItem function of interface, defined in listDevices_test.go file, or api with the input we've provided.
+		result, err := self.DynamoDB.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: requestItems})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Responses[tableName]...)
+		requestItems = result.UnprocessedKeys
+	}
+
+	return items, nil
+}
+
+// Devices read through the tag index are checked against the whole selector and the filter, sorted by id.
+// The index is updated shortly after the devices, so the tags of the indexed requirement are checked again as well.
+func MatchTags(query ListQuery, items []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+	matched := map[string]map[string]*dynamodb.AttributeValue{}
+	ids := []string{}
+	for _, item := range items {
+		device := types.Device{}
+		// Deserialization/Decoding "item" to Go struct.
+		if err := dynamodbattribute.UnmarshalMap(item, &device); err != nil {
+			return nil, err
+		}
+
+		if device.DeletedAt != "" || !query.Tags.Matches(device.Tags) {
+			continue
+		}
+		values := map[string]string{"id": device.ID, "deviceModel": device.DeviceModel, "name": device.Name, "note": device.Note, "serial": device.Serial, "status": device.Status,
+			"createdAt": device.CreatedAt, "createdBy": device.CreatedBy, "updatedAt": device.UpdatedAt, "updatedBy": device.UpdatedBy}
+		if query.Filter != nil && !query.Filter.Matches(values) {
+			continue
+		}
+		matched[device.ID] = item
+		ids = append(ids, device.ID)
+	}
+
+	sort.Strings(ids)
+	sorted := []map[string]*dynamodb.AttributeValue{}
+	for _, id := range ids {
+		sorted = append(sorted, matched[id])
+	}
+	return sorted, nil
+} // End of MatchTags function
+
+// Sorts a listing which is not read from the device model index. Every device it finds is read and sorted by name, then
+// by id for devices of the same name, and the page starts right after the device of the cursor. Each page reads all of
+// the devices again, so sorted pages of big listings take longer than unsorted ones.
+func SortedPage(query ListQuery) ([]map[string]*dynamodb.AttributeValue, map[string]*dynamodb.AttributeValue, error) {
+	items, err := ReadAll(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return Before(items[i], items[j], query.Sort)
+	})
+
+	// The device of the cursor may have been changed or purged meanwhile, the page starts where it would be.
+	start := 0
+	if len(query.StartKey) != 0 {
+		start = sort.Search(len(items), func(i int) bool {
+			return Before(query.StartKey, items[i], query.Sort)
+		})
+	}
+	if int64(len(items)-start) <= query.Limit {
+		return items[start:], nil, nil
+	}
+
+	page := items[start : start+int(query.Limit)]
+	last := page[len(page)-1]
+	return page, map[string]*dynamodb.AttributeValue{"name": {S: aws.String(Text(last, "name"))}, "id": {S: aws.String(Text(last, "id"))}}, nil
+} // End of SortedPage function
+
+// Reads every device of the listing, from its first page to its last, without its cursor.
+func ReadAll(query ListQuery) ([]map[string]*dynamodb.AttributeValue, error) {
+	query.StartKey, query.Limit = nil, SortReadLimit
+	// Devices are sorted and continued by name and id, so these are read even when the client has not asked for them.
+	if len(query.Fields) != 0 {
+		query.Fields = append(append([]string{}, query.Fields...), "id", "name")
+	}
+
+	all := []map[string]*dynamodb.AttributeValue{}
+	for {
+		var items []map[string]*dynamodb.AttributeValue
+		var lastEvaluatedKey map[string]*dynamodb.AttributeValue
+		var err error
+		if len(query.Tags) != 0 {
+			// No more ids than BatchGet can read at once are looked up at a time.
+			query.Limit = MaxLimit
+			ids, startKey, queryErr := TestAws.QueryByTags(query)
+			if err = queryErr; err == nil {
+				items, err = TestAws.BatchGet(ids)
+			}
+			if err == nil {
+				items, err = MatchTags(query, items)
+				lastEvaluatedKey = startKey
+			}
+		} else if query.Serial != "" {
+			result, queryErr := TestAws.QueryBySerial(query)
+			if err = queryErr; err == nil {
+				items, lastEvaluatedKey = result.Items, result.LastEvaluatedKey
+			}
+		} else {
+			result, scanErr := TestAws.List(query)
+			if err = scanErr; err == nil {
+				items, lastEvaluatedKey = result.Items, result.LastEvaluatedKey
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, items...)
+		if len(lastEvaluatedKey) == 0 {
+			return all, nil
+		}
+		query.StartKey = lastEvaluatedKey
+	}
+} // End of ReadAll function
+
+// Tells if the device of item a comes before the one of item b, by name and then by id, in the order of the listing.
+func Before(a map[string]*dynamodb.AttributeValue, b map[string]*dynamodb.AttributeValue, order string) bool {
+	compared := strings.Compare(Text(a, "name"), Text(b, "name"))
+	if compared == 0 {
+		compared = strings.Compare(Text(a, "id"), Text(b, "id"))
+	}
+	if order == SortByNameDesc {
+		return compared > 0
+	}
+	return compared < 0
+} // End of Before function
+
+// The text of an attribute of the item, empty when it does not have it.
+func Text(item map[string]*dynamodb.AttributeValue, name string) string {
+	if value, ok := item[name]; ok && value != nil {
+		return aws.StringValue(value.S)
+	}
+	return ""
+} // End of Text function
+
+// Deleted devices are always skipped, besides what the client's filter asks for.
+func FilterCondition(node filter.Node) expression.ConditionBuilder {
+	condition := expression.AttributeNotExists(expression.Name("deletedAt"))
+	if node != nil {
+		condition = condition.And(node.Condition())
+	}
+	return condition
+}
+
+// The handler function which will be first started from main function.
+func ListDevices(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// First & foremost we have to validate user input.
+	query, err := ValidateInputs(request)
+	// A missing cursor secret is a misconfiguration of the server, not a mistake of the client.
+	if err == cursor.ErrMissingSecret {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}, nil
+	}
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// Looking up by serial, by device model or by tags reads an index, anything else scans the table.
+	// Only the device model index is sorted by name, other listings are sorted after they have been read.
+	var items []map[string]*dynamodb.AttributeValue
+	var lastEvaluatedKey map[string]*dynamodb.AttributeValue
+	if query.Sort != "" && query.DeviceModel == "" {
+		items, lastEvaluatedKey, err = SortedPage(query)
+	} else if len(query.Tags) != 0 {
+		ids, startKey, queryErr := TestAws.QueryByTags(query)
+		if err = queryErr; err == nil {
+			items, err = TestAws.BatchGet(ids)
+		}
+		if err == nil {
+			items, err = MatchTags(query, items)
+			lastEvaluatedKey = startKey
+		}
+	} else if query.Serial != "" {
+		result, queryErr := TestAws.QueryBySerial(query)
+		if err = queryErr; err == nil {
+			items, lastEvaluatedKey = result.Items, result.LastEvaluatedKey
+		}
+	} else if query.DeviceModel != "" {
+		result, queryErr := TestAws.QueryByDeviceModel(query)
+		if err = queryErr; err == nil {
+			items, lastEvaluatedKey = result.Items, result.LastEvaluatedKey
+		}
+	} else {
+		result, scanErr := TestAws.List(query)
+		if err = scanErr; err == nil {
+			items, lastEvaluatedKey = result.Items, result.LastEvaluatedKey
+		}
+	}
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Checking the result of the DynamoDB scan or query.
+	return ValidateDatabaseResult(items, lastEvaluatedKey, Scope(query), query.Fields), nil
+} // End of ListDevices function
+
+func ValidateInputs(request events.APIGatewayProxyRequest) (ListQuery, error) {
+	query := ListQuery{Limit: DefaultLimit}
+
+	if value, ok := request.QueryStringParameters["limit"]; ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			return ListQuery{}, errors.New("Wrong format: limit must be a positive number.")
+		}
+		query.Limit = parsed
+	}
+
+	// Bigger pages than the maximum are not an error, they will be shortened.
+	if query.Limit > MaxLimit {
+		query.Limit = MaxLimit
+	}
+
+	if serial, ok := request.QueryStringParameters["serial"]; ok {
+		if types.NormalizeSerial(serial) == "" {
+			return ListQuery{}, errors.New("Wrong format: serial can not be empty.")
+		}
+		query.Serial = serial
+
+		query.Match = NormalizedMatch
+		if match, ok := request.QueryStringParameters["match"]; ok {
+			if match != ExactMatch && match != NormalizedMatch {
+				return ListQuery{}, errors.New("Wrong format: match must be " + ExactMatch + " or " + NormalizedMatch + ".")
+			}
+			query.Match = match
+		}
+	}
+
+	if text, ok := request.QueryStringParameters["filter"]; ok {
+		node, err := filter.Parse(text, FilterFields)
+		if err != nil {
+			return ListQuery{}, err
+		}
+		query.FilterText, query.Filter = text, node
+	}
+
+	if text, ok := request.QueryStringParameters["fields"]; ok {
+		names, err := fields.Parse(text, fields.DeviceFields)
+		if err != nil {
+			return ListQuery{}, err
+		}
+		query.Fields = names
+	}
+
+	if text, ok := request.QueryStringParameters["tags"]; ok {
+		selector, err := tags.ParseSelector(text)
+		if err != nil {
+			return ListQuery{}, err
+		}
+		// Devices are found on the tag index, which needs at least one tag they must have.
+		if _, ok := selector.Indexed(); !ok {
+			return ListQuery{}, errors.New("Wrong format: tags needs a requirement of a key or a value which devices must have, i.e: tags=site=berlin,env!=prod.")
+		}
+		if query.Serial != "" {
+			return ListQuery{}, errors.New("Wrong format: tags can not be used with serial.")
+		}
+		query.TagsText, query.Tags = text, selector
+	}
+
+	if value, ok := request.QueryStringParameters["sort"]; ok {
+		if value != SortByName && value != SortByNameDesc {
+			return ListQuery{}, errors.New("Wrong format: sort must be " + SortByName + " or " + SortByNameDesc + ".")
+		}
+		query.Sort = value
+	}
+
+	if query.Serial == "" && len(query.Tags) == 0 {
+		query = UseDeviceModelIndex(query)
+	}
+
+	token := request.QueryStringParameters["cursor"]
+	if token == "" {
+		return query, nil
+	}
+
+	startKey, err := cursor.Decode(Scope(query), token)
+	if err != nil {
+		return ListQuery{}, err
+	}
+	query.StartKey = startKey
+
+	// Everything looks fine, return page size, where the page starts and what to look for.
+	return query, nil
+} // End of ValidateInputs function.
+
+// Moves a "deviceModel eq" comparison of the filter to the key condition of the device model index, and a name
+// comparison with it if there is one. DynamoDB does not allow key attributes of the index in the filter, so the
+// table is scanned when the rest of the filter still compares deviceModel or name.
+func UseDeviceModelIndex(query ListQuery) ListQuery {
+	if query.Filter == nil {
+		return query
+	}
+
+	var deviceModel, nameKey *filter.Comparison
+	rest := []filter.Node{}
+	for _, node := range filter.Conjuncts(query.Filter) {
+		comparison, ok := node.(filter.Comparison)
+		switch {
+		case ok && deviceModel == nil && comparison.Field == "deviceModel" && comparison.Operator == "eq":
+			deviceModel = &comparison
+		case ok && nameKey == nil && comparison.Field == "name" && filter.Operators[comparison.Operator]:
+			nameKey = &comparison
+		default:
+			rest = append(rest, node)
+		}
+	}
+
+	remaining := filter.Join(rest)
+	if deviceModel == nil || (remaining != nil && (filter.References(remaining, "deviceModel") || filter.References(remaining, "name"))) {
+		return query
+	}
+
+	query.DeviceModel, query.NameKey, query.Filter = deviceModel.Value, nameKey, remaining
+	return query
+} // End of UseDeviceModelIndex function
+
+// Cursors only continue the same lookup, with the same filter and order.
+func Scope(query ListQuery) string {
+	params := []string{}
+	if query.Serial != "" {
+		serial := types.NormalizeSerial(query.Serial)
+		if query.Match == ExactMatch {
+			serial = query.Serial
+		}
+		params = append(params, "serial="+serial, "match="+query.Match)
+	}
+	if query.FilterText != "" {
+		params = append(params, "filter="+query.FilterText)
+	}
+	if query.Sort != "" {
+		params = append(params, "sort="+query.Sort)
+	}
+	if query.TagsText != "" {
+		params = append(params, "tags="+query.TagsText)
+	}
+
+	if len(params) == 0 {
+		return CursorScope
+	}
+	return CursorScope + "?" + strings.Join(params, "&")
+} // End of Scope function
+
+func ValidateDatabaseResult(items []map[string]*dynamodb.AttributeValue, lastEvaluatedKey map[string]*dynamodb.AttributeValue, scope string, names []string) events.APIGatewayProxyResponse {
+	page := types.DevicePage{Devices: []types.Device{}}
+
+	// Deserialization/Decoding "items" to Go structs.
+	err := dynamodbattribute.UnmarshalListOfMaps(items, &page.Devices)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}
+	}
+
+	// No LastEvaluatedKey means the last page has been reached and the cursor stays empty.
+	page.NextCursor, err = cursor.Encode(scope, lastEvaluatedKey)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}
+	}
+
+	// Serialization/Encoding page to JSON, with only the desired fields of devices if client has asked for some.
+	jsonResponse, _ := json.Marshal(page)
+	if len(names) != 0 {
+		sparse, _ := fields.SelectPage(page, names)
+		jsonResponse, _ = json.Marshal(sparse)
+	}
+
+	// Return founded page as JSON type with 200 HTTP status code.
+	return events.APIGatewayProxyResponse{
+		Body:       string(jsonResponse),
+		StatusCode: 200,
+	}
+} // End of ValidateDatabaseResult function
+
+func main() {
+	lambda.Start(ListDevices)
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"cursor"
+	"encoding/json"
+	"filter"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	MockDatabaseOutput dynamodb.ScanOutput
+	ExpectedLimit      int64
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked Scan function has received.
+	Input *dynamodb.ScanInput
+	// Last input the mocked Query function has received.
+	QueryInput *dynamodb.QueryInput
+	// Ids of the devices of each tag index entry, in order, i.e: "site=berlin": {"id_a", "id_c"}.
+	TagIndex map[string][]string
+	// Devices the mocked BatchGetItem function reads, by their ids.
+	Devices map[string]map[string]*dynamodb.AttributeValue
+	// Pages of devices the mocked Scan function reads instead of its single device, when there are any.
+	Pages [][]map[string]*dynamodb.AttributeValue
+}
+
+// Custom Scan function for overriding the Scan of listDevices.go for using in test scenarios.
+// Mocking Scan output to a page with one device, followed by a last page without any.
+func (self *MockDynamoDB) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	self.Input = input
+	mockOutput := new(dynamodb.ScanOutput)
+
+	// Pages are continued by their number, the last one has no LastEvaluatedKey.
+	if len(self.Pages) != 0 {
+		page := 0
+		if input.ExclusiveStartKey != nil {
+			page, _ = strconv.Atoi(*input.ExclusiveStartKey["page"].N)
+		}
+		mockOutput.SetItems(self.Pages[page])
+		if page+1 < len(self.Pages) {
+			mockOutput.SetLastEvaluatedKey(map[string]*dynamodb.AttributeValue{"page": {N: aws.String(strconv.Itoa(page + 1))}})
+		}
+		return mockOutput, nil
+	}
+
+	if input.ExclusiveStartKey == nil {
+		mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{MockItem()})
+		mockOutput.SetLastEvaluatedKey(map[string]*dynamodb.AttributeValue{"id": {S: aws.String("id_test")}})
+	}
+	return mockOutput, nil
+}
+
+// Custom Query function for overriding the Query of listDevices.go for using in test scenarios.
+// Mocking Query output to two devices sharing the serial, in a single page.
+// Queries of the tag index are answered from TagIndex, a page of up to limit ids after the given one.
+func (self *MockDynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	self.QueryInput = input
+	if *input.ExpressionAttributeNames["#0"] == "tag" {
+		output := &dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{}}
+		for _, id := range self.TagIndex[*input.ExpressionAttributeValues[":0"].S] {
+			if after, ok := input.ExpressionAttributeValues[":1"]; ok && id <= *after.S {
+				continue
+			}
+			if int64(len(output.Items)) == *input.Limit {
+				output.LastEvaluatedKey = output.Items[len(output.Items)-1]
+				break
+			}
+			output.Items = append(output.Items, map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}})
+		}
+		return output, nil
+	}
+
+	second := MockItem()
+	second["id"] = &dynamodb.AttributeValue{S: aws.String("id_test_2")}
+	return &dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{MockItem(), second}}, nil
+}
+
+// Custom BatchGetItem function for overriding the BatchGetItem of listDevices.go for using in test scenarios.
+// Devices are returned in the reverse order of the keys, as DynamoDB returns them in no particular order.
+func (self *MockDynamoDB) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	output := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]*dynamodb.AttributeValue{}}
+	for table, keysAndAttributes := range input.RequestItems {
+		for _, key := range keysAndAttributes.Keys {
+			if item, ok := self.Devices[*key["id"].S]; ok {
+				output.Responses[table] = append([]map[string]*dynamodb.AttributeValue{item}, output.Responses[table]...)
+			}
+		}
+	}
+	return output, nil
+}
+
+// A device of the mocked tag index, with the given tags.
+func MockTaggedItem(id string, tags map[string]string, deleted bool) map[string]*dynamodb.AttributeValue {
+	item := MockItem()
+	item["id"] = &dynamodb.AttributeValue{S: aws.String(id)}
+	item["tags"] = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{}}
+	for key, value := range tags {
+		item["tags"].M[key] = &dynamodb.AttributeValue{S: aws.String(value)}
+	}
+	if deleted {
+		item["deletedAt"] = &dynamodb.AttributeValue{S: aws.String("2020-01-01T00:00:00Z")}
+	}
+	return item
+}
+
+// Devices tagged with sites and environments, id_e has been deleted.
+func MockTaggedDynamoDB() *MockDynamoDB {
+	return &MockDynamoDB{
+		TagIndex: map[string][]string{
+			"site":        {"id_a", "id_b", "id_c", "id_d", "id_e"},
+			"site=berlin": {"id_a", "id_c", "id_e"},
+			"site=paris":  {"id_b"},
+			"site=rome":   {"id_d"},
+			"env":         {"id_a", "id_b"},
+			"env=lab":     {"id_a"},
+			"env=prod":    {"id_b"},
+		},
+		Devices: map[string]map[string]*dynamodb.AttributeValue{
+			"id_a": MockTaggedItem("id_a", map[string]string{"site": "berlin", "env": "lab"}, false),
+			"id_b": MockTaggedItem("id_b", map[string]string{"site": "paris", "env": "prod"}, false),
+			"id_c": MockTaggedItem("id_c", map[string]string{"site": "berlin"}, false),
+			"id_d": MockTaggedItem("id_d", map[string]string{"site": "rome"}, false),
+			"id_e": MockTaggedItem("id_e", map[string]string{"site": "berlin"}, true),
+		},
+	}
+}
+
+func MockItem() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":          &dynamodb.AttributeValue{S: aws.String("id_test")},
+		"deviceModel": &dynamodb.AttributeValue{S: aws.String("deviceModel_test")},
+		"name":        &dynamodb.AttributeValue{S: aws.String("name_test")},
+		"note":        &dynamodb.AttributeValue{S: aws.String("note_test")},
+		"serial":      &dynamodb.AttributeValue{S: aws.String("serial_test")},
+	}
+}
+
+// List function in listDevices.go signature: input: (limit int64, startKey map[string]*dynamodb.AttributeValue), output: (*dynamodb.ScanOutput, error)
+func TestList(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	response, err := test_aws.List(ListQuery{Limit: 10})
+	if err != nil || len(response.Items) != 1 || *mock.Input.Limit != 10 || mock.Input.ExclusiveStartKey != nil || *mock.Input.FilterExpression != "attribute_not_exists (#0)" || *mock.Input.ExpressionAttributeNames["#0"] != "deletedAt" {
+		t.Errorf("** First page scan ** \n \t<resulted output: \n%s> \n<resulted input: \n%s>", response.GoString(), mock.Input.GoString())
+	}
+
+	response, err = test_aws.List(ListQuery{Limit: 10, StartKey: map[string]*dynamodb.AttributeValue{"id": {S: aws.String("id_test")}}})
+	if err != nil || len(response.Items) != 0 || mock.Input.ExclusiveStartKey == nil {
+		t.Errorf("** Next page scan ** \n \t<resulted output: \n%s> \n<resulted input: \n%s>", response.GoString(), mock.Input.GoString())
+	}
+
+	// Only the desired fields are read, the whole item is read by default.
+	test_aws.List(ListQuery{Limit: 10, Fields: []string{"id", "name"}})
+	if mock.Input.ProjectionExpression == nil || *mock.Input.ProjectionExpression != "#1, #2" || *mock.Input.ExpressionAttributeNames["#2"] != "name" {
+		t.Errorf("** Scan of desired fields ** \n \t<resulted input: \n%s>", mock.Input.GoString())
+	}
+} // End of TestList function
+
+// QueryBySerial function in listDevices.go signature: input: (serial string, match string, limit int64, startKey map[string]*dynamodb.AttributeValue), output: (*dynamodb.QueryOutput, error)
+func TestQueryBySerial(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	response, err := test_aws.QueryBySerial(ListQuery{Serial: " a02 0000102", Match: NormalizedMatch, Limit: 10})
+	input := mock.QueryInput
+	if err != nil || len(response.Items) != 2 || *input.IndexName != SerialIndex || len(input.ExpressionAttributeValues) != 1 || *input.ExpressionAttributeValues[":0"].S != "a020000102" {
+		t.Errorf("** Normalized serial query ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+
+	_, err = test_aws.QueryBySerial(ListQuery{Serial: "A020000102", Match: ExactMatch, Limit: 10})
+	input = mock.QueryInput
+	if err != nil || len(input.ExpressionAttributeValues) != 2 {
+		t.Errorf("** Exact serial query ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+} // End of TestQueryBySerial function
+
+// QueryByDeviceModel function in listDevices.go signature: input: (query ListQuery), output: (*dynamodb.QueryOutput, error)
+func TestQueryByDeviceModel(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	query, _ := ValidateInputs(events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{
+		"filter": "deviceModel eq '/devicemodels/id1' and name startsWith 'Sen' and note contains 'it''s'",
+		"sort":   "-name",
+	}})
+	_, err := test_aws.QueryByDeviceModel(query)
+
+	// User input has to end up in attribute values only.
+	input := mock.QueryInput
+	values := []string{}
+	for _, value := range input.ExpressionAttributeValues {
+		values = append(values, *value.S)
+	}
+	sort.Strings(values)
+	if err != nil || *input.IndexName != DeviceModelIndex || *input.ScanIndexForward || strings.Contains(*input.KeyConditionExpression+*input.FilterExpression, "Sen") || strings.Join(values, ",") != "/devicemodels/id1,Sen,it's" {
+		t.Errorf("** Querying the device model index with the filter ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+} // End of TestQueryByDeviceModel function
+
+// QueryByTags function in listDevices.go signature: input: (query ListQuery), output: ([]string, map[string]*dynamodb.AttributeValue, error)
+func TestQueryByTags(t *testing.T) {
+	test_aws := &AmazonWebServices{DynamoDB: MockTaggedDynamoDB()}
+
+	// The first two ids of both values, not the first two of each.
+	query, _ := ValidateInputs(events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tags": "env!=prod, site in (berlin, paris)", "limit": "2"}})
+	ids, startKey, err := test_aws.QueryByTags(query)
+	if err != nil || strings.Join(ids, ",") != "id_a,id_b" || startKey == nil || *startKey["id"].S != "id_b" {
+		t.Errorf("** First page of a set of values ** \n \t<expected ids: id_a,id_b> <resulted ids: %v> <resulted start key: %v> <resulted error: %v>", ids, startKey, err)
+	}
+
+	query.StartKey = startKey
+	ids, startKey, err = test_aws.QueryByTags(query)
+	if err != nil || strings.Join(ids, ",") != "id_c,id_e" || startKey != nil {
+		t.Errorf("** Last page of a set of values ** \n \t<expected ids: id_c,id_e> <resulted ids: %v> <resulted start key: %v> <resulted error: %v>", ids, startKey, err)
+	}
+} // End of TestQueryByTags function
+
+// UseDeviceModelIndex function in listDevices.go signature: input: (query ListQuery), output: (ListQuery)
+func TestUseDeviceModelIndex(t *testing.T) {
+	TestCases := map[string]string{
+		"deviceModel eq 'a' and name ge 'b' and note eq 'c'":    "a",
+		"deviceModel eq 'a' or name eq 'b'":                     "",
+		"deviceModel startsWith 'a'":                            "",
+		"deviceModel eq 'a' and name ge 'b' and name le 'c'":    "",
+		"deviceModel eq 'a' and (note eq 'b' or serial eq 'c')": "a",
+	}
+
+	for text, expectedDeviceModel := range TestCases {
+		node, _ := filter.Parse(text, FilterFields)
+		query := UseDeviceModelIndex(ListQuery{Filter: node})
+		if query.DeviceModel != expectedDeviceModel {
+			t.Errorf("** Testing: %s ** \n \t<expected device model: %s> <resulted device model: %s>", text, expectedDeviceModel, query.DeviceModel)
+		}
+	}
+} // End of TestUseDeviceModelIndex function
+
+// ValidateInputs function in listDevices.go signature: input: (request events.APIGatewayProxyRequest), output: (int64, map[string]*dynamodb.AttributeValue, error)
+func TestValidateInputs(t *testing.T) {
+	os.Setenv("CURSOR_SECRET", "secret_test")
+	validCursor, _ := cursor.Encode(CursorScope, map[string]*dynamodb.AttributeValue{"id": {S: aws.String("id_test")}})
+	foreignCursor, _ := cursor.Encode("other_scope", map[string]*dynamodb.AttributeValue{"id": {S: aws.String("id_test")}})
+
+	TestCases := []TestCase{
+		{
+			Name:          "** Testing: No parameters. **",
+			Request:       events.APIGatewayProxyRequest{},
+			ExpectedLimit: DefaultLimit,
+		},
+
+		{
+			Name:          "** Testing: Limit within maximum. **",
+			Request:       events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"limit": "5"}},
+			ExpectedLimit: 5,
+		},
+
+		{
+			Name:          "** Testing: Limit above maximum. **",
+			Request:       events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"limit": "5000"}},
+			ExpectedLimit: MaxLimit,
+		},
+
+		{
+			Name:         "** Testing: Negative limit. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"limit": "-1"}},
+			ExpectedBody: "Wrong format: limit must be a positive number.",
+		},
+
+		{
+			Name:         "** Testing: Limit is not a number. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"limit": "ten"}},
+			ExpectedBody: "Wrong format: limit must be a positive number.",
+		},
+
+		{
+			Name:          "** Testing: Valid cursor. **",
+			Request:       events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"cursor": validCursor}},
+			ExpectedLimit: DefaultLimit,
+		},
+
+		{
+			Name:         "** Testing: Blank serial. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"serial": " "}},
+			ExpectedBody: "Wrong format: serial can not be empty.",
+		},
+
+		{
+			Name:         "** Testing: Unknown match. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"serial": "A020000102", "match": "fuzzy"}},
+			ExpectedBody: "Wrong format: match must be exact or normalized.",
+		},
+
+		{
+			Name:         "** Testing: Cursor of listing all devices used for a serial lookup. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"serial": "A020000102", "cursor": validCursor}},
+			ExpectedBody: "Invalid cursor.",
+		},
+
+		{
+			Name:          "** Testing: Filter on the time of the last write. **",
+			Request:       events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"filter": "updatedAt ge '2020-01-02T00:00:00Z' and createdBy eq 'alice'"}},
+			ExpectedLimit: DefaultLimit,
+		},
+
+		{
+			Name:         "** Testing: Unknown filter field. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"filter": "name eq 'Sensor' and color eq 'red'"}},
+			ExpectedBody: "Wrong filter: unknown field 'color' at position 22.",
+		},
+
+		{
+			Name:         "** Testing: Unsupported filter operator. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"filter": "name like 'Sen%'"}},
+			ExpectedBody: "Wrong filter: unsupported operator 'like' at position 6.",
+		},
+
+		{
+			Name:         "** Testing: Unquoted filter value. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"filter": "name eq Sensor"}},
+			ExpectedBody: "Wrong filter: expected a quoted value instead of 'Sensor' at position 9.",
+		},
+
+		{
+			Name:         "** Testing: Unclosed parenthesis. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"filter": "(name eq 'Sensor'"}},
+			ExpectedBody: "Wrong filter: expected ')' at position 18.",
+		},
+
+		{
+			Name:         "** Testing: Unterminated filter value. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"filter": "name eq 'Sensor"}},
+			ExpectedBody: "Wrong filter: unterminated value at position 9.",
+		},
+
+		{
+			Name:          "** Testing: Filter with not and or. **",
+			Request:       events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"filter": "not (name eq 'a' or note contains 'b')"}},
+			ExpectedLimit: DefaultLimit,
+		},
+
+		{
+			Name:         "** Testing: Unknown field in fields. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"fields": "id,serialKey"}},
+			ExpectedBody: "Wrong format: unknown field serialKey in fields, fields can be id, deviceModel, name, note, serial, tags, attributes, status, statusReason, createdAt, createdBy, updatedAt, updatedBy, version.",
+		},
+
+		{
+			Name:          "** Testing: Desired fields. **",
+			Request:       events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"fields": "id, name,id"}},
+			ExpectedLimit: DefaultLimit,
+		},
+
+		{
+			Name:          "** Testing: Sort without device model. **",
+			Request:       events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"filter": "name eq 'Sensor'", "sort": "name"}},
+			ExpectedLimit: DefaultLimit,
+		},
+
+		{
+			Name:          "** Testing: Sort with tags. **",
+			Request:       events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tags": "site=berlin", "sort": "-name"}},
+			ExpectedLimit: DefaultLimit,
+		},
+
+		{
+			Name:         "** Testing: Unknown sort. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"filter": "deviceModel eq '/devicemodels/id1'", "sort": "serial"}},
+			ExpectedBody: "Wrong format: sort must be name or -name.",
+		},
+
+		{
+			Name:         "** Testing: Cursor of listing all devices used with a filter. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"filter": "name eq 'Sensor'", "cursor": validCursor}},
+			ExpectedBody: "Invalid cursor.",
+		},
+
+		{
+			Name:          "** Testing: Tag selector. **",
+			Request:       events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tags": "site=berlin,env!=prod,example.com/tier notin (a,b),!canary"}},
+			ExpectedLimit: DefaultLimit,
+		},
+
+		{
+			Name:         "** Testing: Tag selector without a requirement the index can look up. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tags": "env!=prod"}},
+			ExpectedBody: "Wrong format: tags needs a requirement of a key or a value which devices must have, i.e: tags=site=berlin,env!=prod.",
+		},
+
+		{
+			Name:         "** Testing: Tag selector with a wrong key. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tags": "-site=berlin"}},
+			ExpectedBody: "Wrong format: tag key -site must be 1 to 63 letters, digits, '-', '_' or '.', starting and ending with a letter or a digit.",
+		},
+
+		{
+			Name:         "** Testing: Tag selector with an unclosed set. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tags": "site in (berlin,paris"}},
+			ExpectedBody: "Wrong format: parentheses of tags do not match, i.e: tags=site in (berlin,paris).",
+		},
+
+		{
+			Name:         "** Testing: Tag selector with a serial. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tags": "site=berlin", "serial": "A020000102"}},
+			ExpectedBody: "Wrong format: tags can not be used with serial.",
+		},
+
+		{
+			Name:         "** Testing: Cursor of listing all devices used with tags. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tags": "site=berlin", "cursor": validCursor}},
+			ExpectedBody: "Invalid cursor.",
+		},
+
+		{
+			Name:         "** Testing: Tampered cursor. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"cursor": "x" + validCursor}},
+			ExpectedBody: "Invalid cursor.",
+		},
+
+		{
+			Name:         "** Testing: Cursor of another endpoint. **",
+			Request:      events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"cursor": foreignCursor}},
+			ExpectedBody: "Invalid cursor.",
+		},
+	}
+
+	for _, test := range TestCases {
+		// Executing each test cases scenario.
+		query, err := ValidateInputs(test.Request)
+		limit := query.Limit
+
+		resultedBody := ""
+		if err != nil {
+			resultedBody = err.Error()
+		}
+		if limit != test.ExpectedLimit || resultedBody != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected limit: %d> <resulted limit: %d> \n \t<expected error: %s> <resulted error: %s>", test.Name, test.ExpectedLimit, limit, test.ExpectedBody, resultedBody)
+		}
+	}
+} // End of TestValidateInputs function
+
+// ListDevices function in listDevices.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestListDevices(t *testing.T) {
+	os.Setenv("CURSOR_SECRET", "secret_test")
+	nextCursor, _ := cursor.Encode(CursorScope, map[string]*dynamodb.AttributeValue{"id": {S: aws.String("id_test")}})
+
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	TestCases := []TestCase{
+		{
+			Name:               "** Testing: Wrong limit. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"limit": "0"}},
+			ExpectedBody:       "Wrong format: limit must be a positive number.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: First page. **",
+			Request:            events.APIGatewayProxyRequest{},
+			ExpectedBody:       "{\"devices\":[{\"id\":\"id_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\"}],\"nextCursor\":\"" + nextCursor + "\"}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Devices sharing a serial. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"serial": "a02 0000102"}},
+			ExpectedBody:       "{\"devices\":[{\"id\":\"id_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\"},{\"id\":\"id_test_2\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\"}]}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: First page of desired fields. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"fields": "name,id"}},
+			ExpectedBody:       "{\"devices\":[{\"id\":\"id_test\",\"name\":\"name_test\"}],\"nextCursor\":\"" + nextCursor + "\"}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Last page. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"cursor": nextCursor}},
+			ExpectedBody:       "{\"devices\":[]}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range TestCases {
+		// Executing each test cases scenario.
+		response, _ := ListDevices(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// Devices found on the tag index are checked against the rest of the selector, and deleted ones are skipped.
+	TestAws = &AmazonWebServices{DynamoDB: MockTaggedDynamoDB()}
+	params := map[string]string{"tags": "site in (berlin,paris),env!=prod", "fields": "id,tags", "limit": "2"}
+	response, _ := ListDevices(events.APIGatewayProxyRequest{QueryStringParameters: params})
+	page := struct{ NextCursor string }{}
+	json.Unmarshal([]byte(response.Body), &page)
+	if response.StatusCode != 200 || !strings.HasPrefix(response.Body, "{\"devices\":[{\"id\":\"id_a\",\"tags\":{\"env\":\"lab\",\"site\":\"berlin\"}}],\"nextCursor\":") {
+		t.Errorf("** Testing: First page of a tag selector. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+
+	params["cursor"] = page.NextCursor
+	response, _ = ListDevices(events.APIGatewayProxyRequest{QueryStringParameters: params})
+	if response.StatusCode != 200 || response.Body != "{\"devices\":[{\"id\":\"id_c\",\"tags\":{\"site\":\"berlin\"}}]}" {
+		t.Errorf("** Testing: Last page of a tag selector. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+
+	// The filter is checked on the devices as well.
+	response, _ = ListDevices(events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tags": "site", "filter": "id ge 'id_c' and not (id eq 'id_d')", "fields": "id"}})
+	if response.StatusCode != 200 || response.Body != "{\"devices\":[{\"id\":\"id_c\"}]}" {
+		t.Errorf("** Testing: Tag selector with a filter. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+
+	// Sorting by name without a device model reads every page of the table, the cursor continues after the last device.
+	named := func(id string, name string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}, "name": {S: aws.String(name)}}
+	}
+	mock := &MockDynamoDB{Pages: [][]map[string]*dynamodb.AttributeValue{{named("id_1", "b"), named("id_2", "a")}, {named("id_3", "c"), named("id_4", "a")}}}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+	params = map[string]string{"sort": "name", "limit": "2", "fields": "id"}
+	response, _ = ListDevices(events.APIGatewayProxyRequest{QueryStringParameters: params})
+	page.NextCursor = ""
+	json.Unmarshal([]byte(response.Body), &page)
+	if response.StatusCode != 200 || !strings.HasPrefix(response.Body, "{\"devices\":[{\"id\":\"id_2\"},{\"id\":\"id_4\"}],\"nextCursor\":") || *mock.Input.ProjectionExpression != "#1, #2" || *mock.Input.ExpressionAttributeNames["#2"] != "name" {
+		t.Errorf("** Testing: First page sorted by name. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+
+	params["cursor"] = page.NextCursor
+	response, _ = ListDevices(events.APIGatewayProxyRequest{QueryStringParameters: params})
+	if response.StatusCode != 200 || response.Body != "{\"devices\":[{\"id\":\"id_1\"},{\"id\":\"id_3\"}]}" {
+		t.Errorf("** Testing: Last page sorted by name. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+
+	// Devices of the same name are in the order of their ids, backwards as well.
+	response, _ = ListDevices(events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"sort": "-name", "fields": "id"}})
+	if response.StatusCode != 200 || response.Body != "{\"devices\":[{\"id\":\"id_3\"},{\"id\":\"id_1\"},{\"id\":\"id_4\"},{\"id\":\"id_2\"}]}" {
+		t.Errorf("** Testing: Sorted by name descending. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+
+	// Devices found on the tag index are sorted the same way.
+	TestAws = &AmazonWebServices{DynamoDB: MockTaggedDynamoDB()}
+	response, _ = ListDevices(events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"tags": "site", "sort": "-name", "fields": "id"}})
+	if response.StatusCode != 200 || response.Body != "{\"devices\":[{\"id\":\"id_d\"},{\"id\":\"id_c\"},{\"id\":\"id_b\"},{\"id\":\"id_a\"}]}" {
+		t.Errorf("** Testing: Tag selector sorted by name descending. ** \n \t<resulted error-code: %d> <resulted body: %s>", response.StatusCode, response.Body)
+	}
+} // End of TestListDevices function
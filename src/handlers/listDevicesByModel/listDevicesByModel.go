@@ -0,0 +1,249 @@
+package main
+
+import (
+	"cursor"
+	"encoding/json"
+	"errors"
+	"fields"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"net/url"
+	"os"
+	"strconv"
+	"types"
+)
+
+// Number of devices returned when client does not ask for a specific page size.
+const DefaultLimit = 25
+
+// Server enforced maximum page size, bigger limits will be lowered to it.
+const MaxLimit = 100
+
+// Global secondary index of the devices table on the device model, sorted by name.
+const DeviceModelIndex = "deviceModel-name-index"
+
+// Devices reference their model by this prefix and the id of the model.
+const DeviceModelPrefix = "/devicemodels/"
+
+// Sort orders of the devices, by name ascending or descending.
+const (
+	SortByName     = "name"
+	SortByNameDesc = "-name"
+)
+
+// Struct containing what a list request asks for, after validation.
+type ListQuery struct {
+	DeviceModel string
+	Sort        string
+	Limit       int64
+	StartKey    map[string]*dynamodb.AttributeValue
+	// Fields which client has asked for, all of them when empty.
+	Fields []string
+}
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's Query function inside, on the device model index.
+// The index is sorted by name, so sorting is only a matter of reading it forward or backward.
+func (self *AmazonWebServices) QueryByDeviceModel(query ListQuery) (*dynamodb.QueryOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	keyCondition := expression.Key("deviceModel").Equal(expression.Value(query.DeviceModel))
+	filter := expression.AttributeNotExists(expression.Name("deletedAt"))
+
+	expr, err := fields.Project(expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(filter), query.Fields).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	// Deleted devices are skipped. Limit is applied before filtering, so a page may have less devices than limit.
+	var input = &dynamodb.QueryInput{
+		TableName:                 tableName,
+		IndexName:                 aws.String(DeviceModelIndex),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ProjectionExpression:      expr.Projection(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(query.Sort != SortByNameDesc),
+		Limit:                     aws.Int64(query.Limit),
+	}
+	// Continue right after the last item of the previous page.
+	if len(query.StartKey) != 0 {
+		input.ExclusiveStartKey = query.StartKey
+	}
+
+	// Calling either Query function of interface, defined in listDevicesByModel_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Query(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func ListDevicesByModel(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id of the model which user has sent through GET method.
+	modelId := request.PathParameters["modelId"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if modelId == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : modelId",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// First & foremost we have to validate user input.
+	query, err := ValidateInputs(request)
+	// A missing cursor secret is a misconfiguration of the server, not a mistake of the client.
+	if err == cursor.ErrMissingSecret {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}, nil
+	}
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	result, err := TestAws.QueryByDeviceModel(query)
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Checking the result of the DynamoDB query.
+	return ValidateDatabaseResult(result, Scope(query), query.Fields), nil
+} // End of ListDevicesByModel function
+
+func ValidateInputs(request events.APIGatewayProxyRequest) (ListQuery, error) {
+	query := ListQuery{
+		DeviceModel: DeviceModelPrefix + request.PathParameters["modelId"],
+		Sort:        SortByName,
+		Limit:       DefaultLimit,
+	}
+
+	if value, ok := request.QueryStringParameters["limit"]; ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			return ListQuery{}, errors.New("Wrong format: limit must be a positive number.")
+		}
+		query.Limit = parsed
+	}
+
+	// Bigger pages than the maximum are not an error, they will be shortened.
+	if query.Limit > MaxLimit {
+		query.Limit = MaxLimit
+	}
+
+	if value, ok := request.QueryStringParameters["sort"]; ok {
+		if value != SortByName && value != SortByNameDesc {
+			return ListQuery{}, errors.New("Wrong format: sort must be " + SortByName + " or " + SortByNameDesc + ".")
+		}
+		query.Sort = value
+	}
+
+	if text, ok := request.QueryStringParameters["fields"]; ok {
+		names, err := fields.Parse(text, fields.DeviceFields)
+		if err != nil {
+			return ListQuery{}, err
+		}
+		query.Fields = names
+	}
+
+	token := request.QueryStringParameters["cursor"]
+	if token == "" {
+		return query, nil
+	}
+
+	startKey, err := cursor.Decode(Scope(query), token)
+	if err != nil {
+		return ListQuery{}, err
+	}
+	query.StartKey = startKey
+
+	// Everything looks fine, return the model, the order, page size, where the page starts and the desired fields.
+	return query, nil
+} // End of ValidateInputs function.
+
+// Cursors only continue the same model in the same order. Values are escaped, so no model can pass for another scope.
+func Scope(query ListQuery) string {
+	return "devicemodels?" + url.Values{"deviceModel": {query.DeviceModel}, "sort": {query.Sort}}.Encode()
+} // End of Scope function
+
+func ValidateDatabaseResult(result *dynamodb.QueryOutput, scope string, names []string) events.APIGatewayProxyResponse {
+	page := types.DevicePage{Devices: []types.Device{}}
+
+	// Deserialization/Decoding "result.Items" to Go structs.
+	err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &page.Devices)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}
+	}
+
+	// No LastEvaluatedKey means the last page has been reached and the cursor stays empty.
+	page.NextCursor, err = cursor.Encode(scope, result.LastEvaluatedKey)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}
+	}
+
+	// Serialization/Encoding page to JSON, with only the desired fields of devices if client has asked for some.
+	jsonResponse, _ := json.Marshal(page)
+	if len(names) != 0 {
+		sparse, _ := fields.SelectPage(page, names)
+		jsonResponse, _ = json.Marshal(sparse)
+	}
+
+	// Return founded page as JSON type with 200 HTTP status code.
+	return events.APIGatewayProxyResponse{
+		Body:       string(jsonResponse),
+		StatusCode: 200,
+	}
+} // End of ValidateDatabaseResult function
+
+func main() {
+	lambda.Start(ListDevicesByModel)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"cursor"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked Query function has received.
+	Input *dynamodb.QueryInput
+}
+
+// Custom Query function for overriding the Query of listDevicesByModel.go for using in test scenarios.
+// Mocking Query output to a page with one device, followed by a last page without any.
+func (self *MockDynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	self.Input = input
+	mockOutput := new(dynamodb.QueryOutput)
+
+	if input.ExclusiveStartKey == nil {
+		mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{MockItem()})
+		mockOutput.SetLastEvaluatedKey(MockKey())
+	}
+	return mockOutput, nil
+}
+
+func MockItem() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":          &dynamodb.AttributeValue{S: aws.String("id_test")},
+		"deviceModel": &dynamodb.AttributeValue{S: aws.String("/devicemodels/model_test")},
+		"name":        &dynamodb.AttributeValue{S: aws.String("name_test")},
+		"note":        &dynamodb.AttributeValue{S: aws.String("note_test")},
+		"serial":      &dynamodb.AttributeValue{S: aws.String("serial_test")},
+	}
+}
+
+func MockKey() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":          {S: aws.String("id_test")},
+		"deviceModel": {S: aws.String("/devicemodels/model_test")},
+		"name":        {S: aws.String("name_test")},
+	}
+}
+
+// QueryByDeviceModel function in listDevicesByModel.go signature: input: (query ListQuery), output: (*dynamodb.QueryOutput, error)
+func TestQueryByDeviceModel(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	response, err := test_aws.QueryByDeviceModel(ListQuery{DeviceModel: "/devicemodels/model_test", Sort: SortByNameDesc, Limit: 10})
+	input := mock.Input
+	if err != nil || len(response.Items) != 1 || *input.IndexName != DeviceModelIndex || *input.ScanIndexForward || *input.Limit != 10 || *input.ExpressionAttributeValues[":0"].S != "/devicemodels/model_test" {
+		t.Errorf("** Querying by device model, descending ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+} // End of TestQueryByDeviceModel function
+
+// Scope function in listDevicesByModel.go signature: input: (query ListQuery), output: (string)
+func TestScope(t *testing.T) {
+	// A model id can have any character, it must not change the sort order of the scope.
+	crafted := Scope(ListQuery{DeviceModel: "/devicemodels/a&sort=-name", Sort: SortByName})
+	if crafted == Scope(ListQuery{DeviceModel: "/devicemodels/a", Sort: SortByNameDesc}) || crafted != "devicemodels?deviceModel=%2Fdevicemodels%2Fa%26sort%3D-name&sort=name" {
+		t.Errorf("** Scope of a crafted model id ** \n \t<resulted scope: %s>", crafted)
+	}
+} // End of TestScope function
+
+// ListDevicesByModel function in listDevicesByModel.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestListDevicesByModel(t *testing.T) {
+	os.Setenv("CURSOR_SECRET", "secret_test")
+	nextCursor, _ := cursor.Encode("devicemodels?deviceModel=%2Fdevicemodels%2Fmodel_test&sort=name", MockKey())
+	descendingCursor, _ := cursor.Encode("devicemodels?deviceModel=%2Fdevicemodels%2Fmodel_test&sort=-name", MockKey())
+
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+	path := map[string]string{"modelId": "model_test"}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty model id. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": ""}},
+			ExpectedBody:       "Missing field : modelId",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Unknown sort. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: path, QueryStringParameters: map[string]string{"sort": "serial"}},
+			ExpectedBody:       "Wrong format: sort must be name or -name.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Unknown field in fields. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: path, QueryStringParameters: map[string]string{"fields": "id,color"}},
+			ExpectedBody:       "Wrong format: unknown field color in fields, fields can be id, deviceModel, name, note, serial, tags, attributes, status, statusReason, createdAt, createdBy, updatedAt, updatedBy, version.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Cursor of another sort order. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: path, QueryStringParameters: map[string]string{"cursor": descendingCursor}},
+			ExpectedBody:       "Invalid cursor.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: First page. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: path},
+			ExpectedBody:       "{\"devices\":[{\"id\":\"id_test\",\"deviceModel\":\"/devicemodels/model_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\"}],\"nextCursor\":\"" + nextCursor + "\"}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: First page of desired fields. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: path, QueryStringParameters: map[string]string{"fields": "id,name"}},
+			ExpectedBody:       "{\"devices\":[{\"id\":\"id_test\",\"name\":\"name_test\"}],\"nextCursor\":\"" + nextCursor + "\"}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Last page. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: path, QueryStringParameters: map[string]string{"cursor": nextCursor}},
+			ExpectedBody:       "{\"devices\":[]}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := ListDevicesByModel(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestListDevicesByModel function
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"actor"
+	"clock"
+	"devicemodels"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"headers"
+	"os"
+	"reflect"
+	"sort"
+	"types"
+	"versioning"
+)
+
+// Media type of RFC 7396 JSON Merge Patch documents.
+const MergePatchMediaType = "application/merge-patch+json"
+
+// Number of times a patch is tried again, when the device is changed by others while it's being patched.
+const MaxAttempts = 3
+
+// Fields a merge patch is allowed to change. The id is the key of the item and can never be changed.
+var PatchableFields = map[string]bool{
+	"deviceModel": true,
+	"name":        true,
+	"note":        true,
+	"serial":      true,
+	"attributes":  true,
+}
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside.
+func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	// Calling either GetItem function of interface, defined in patchDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// Only the given attributes are written, the whole item is returned as it is after the update.
+// The item is only updated if it still has the version which the changes have been made on.
+// A new device model is checked in the same transaction as the changes, see devicemodels.Update.
+func (self *AmazonWebServices) Update(device types.Device, changes map[string]interface{}, version int64) (*dynamodb.UpdateItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	// Sorting names keeps the generated expression the same for the same changes.
+	names := []string{}
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Names and values are passed as expression attribute names and values, never as part of the expression.
+	// Fields which the patch has removed are removed from the item.
+	update := expression.UpdateBuilder{}
+	for _, name := range names {
+		if changes[name] == nil {
+			update = update.Remove(expression.Name(name))
+		} else {
+			update = update.Set(expression.Name(name), expression.Value(changes[name]))
+		}
+	}
+	// Patching must never create a new item, nor change a deleted one.
+	condition := expression.AttributeExists(expression.Name("id")).
+		And(expression.AttributeNotExists(expression.Name("deletedAt"))).
+		And(versioning.Condition(version))
+
+	expr, err := expression.NewBuilder().WithUpdate(versioning.Increment(update)).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(device.ID),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	}
+
+	// Calling either UpdateItem or TransactWriteItems function of interface, defined in patchDevice_test.go file, or api with the input we've provided.
+	_, modelChanged := changes["deviceModel"]
+	result, err := devicemodels.Update(self.DynamoDB, input, device, modelChanged)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func PatchDevice(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which user has sent through PATCH method.
+	id := request.PathParameters["id"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : id",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// Only merge patch documents are understood, return HTTP error code 415 for anything else.
+	if headers.MediaType(request.Headers) != MergePatchMediaType {
+		return events.APIGatewayProxyResponse{
+			Body:       "Unsupported media type: Content-Type must be " + MergePatchMediaType + ".",
+			StatusCode: 415,
+		}, nil
+	}
+
+	// First & foremost we have to validate user input.
+	patch, err := ValidateInputs(request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// Patching can be made safe by sending the ETag of the device in If-Match header.
+	expectedVersion, checkVersion, err := versioning.IfMatch(request.Headers)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		// The patch is applied to the device as it is stored right now.
+		result, err := TestAws.Get(id)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+
+		CurrentDevice := types.Device{}
+		dynamodbattribute.UnmarshalMap(result.Item, &CurrentDevice)
+
+		// Deleted devices can only be restored, not patched.
+		if len(result.Item) == 0 || CurrentDevice.DeletedAt != "" {
+			return events.APIGatewayProxyResponse{
+				Body:       "Desired device not found.",
+				StatusCode: 404,
+			}, nil
+		}
+
+		// The device has another version than the client has expected, return HTTP error code 412.
+		if checkVersion && CurrentDevice.Version != expectedVersion {
+			return versioning.PreconditionFailed(CurrentDevice.Version), nil
+		}
+
+		PatchedDevice, changes, err := ApplyPatch(CurrentDevice, patch)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       err.Error(),
+				StatusCode: 400,
+			}, nil
+		}
+
+		// A new device model has to exist, so typos do not create phantom models, and attributes have to match its schema.
+		_, modelChanged := changes["deviceModel"]
+		if _, attributesChanged := changes["attributes"]; modelChanged || attributesChanged {
+			if err = devicemodels.Check(TestAws.DynamoDB, PatchedDevice); err != nil {
+				if devicemodels.IsClientError(err) {
+					return events.APIGatewayProxyResponse{
+						Body:       err.Error(),
+						StatusCode: 400,
+					}, nil
+				}
+				return events.APIGatewayProxyResponse{
+					Body:       "Internal Server Error\nDatabase error.",
+					StatusCode: 500,
+				}, nil
+			}
+		}
+
+		// Nothing to write, the device already looks like the patched one.
+		if len(changes) == 0 {
+			jsonResponse, _ := json.Marshal(PatchedDevice)
+			return events.APIGatewayProxyResponse{
+				Body:       string(jsonResponse),
+				Headers:    map[string]string{"ETag": versioning.ETag(PatchedDevice.Version)},
+				StatusCode: 200,
+			}, nil
+		}
+
+		// Stamped with when and by whom it has been patched, for auditing and the history of the device.
+		changes[actor.UpdatedAt], changes[actor.UpdatedBy] = clock.Timestamp(), actor.From(request)
+		updated, err := TestAws.Update(PatchedDevice, changes, CurrentDevice.Version)
+
+		// The device has been changed or deleted since it was read. Read it again and find out what happened.
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			if attempt < MaxAttempts {
+				continue
+			}
+			// Too many writers on the same device, return HTTP error code 409.
+			return events.APIGatewayProxyResponse{
+				Body:       "Conflict: The device is being changed by others, please retry.",
+				StatusCode: 409,
+			}, nil
+		}
+
+		// The device model has been deleted since it was checked, or is being deleted. Return HTTP error code 400.
+		if devicemodels.IsClientError(err) {
+			return events.APIGatewayProxyResponse{
+				Body:       err.Error(),
+				StatusCode: 400,
+			}, nil
+		}
+
+		// If internal database errors occurred, return HTTP error code 500.
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+
+		// Return the whole device, as DynamoDB has stored it.
+		UpdatedDevice := types.Device{}
+		dynamodbattribute.UnmarshalMap(updated.Attributes, &UpdatedDevice)
+
+		jsonResponse, _ := json.Marshal(UpdatedDevice)
+		return events.APIGatewayProxyResponse{
+			Body:    string(jsonResponse),
+			Headers: map[string]string{"ETag": versioning.ETag(UpdatedDevice.Version)},
+			// Everything looks fine, return HTTP 200
+			StatusCode: 200,
+		}, nil
+	}
+} // End of PatchDevice function
+
+func ValidateInputs(request events.APIGatewayProxyRequest) (map[string]interface{}, error) {
+	patch := map[string]interface{}{}
+
+	if len(request.Body) == 0 {
+		return nil, errors.New("No inputs provided, please provide inputs in JSON format.")
+	}
+
+	// A merge patch which is not a JSON object would replace the whole device, which is never valid.
+	if err := json.Unmarshal([]byte(request.Body), &patch); err != nil {
+		return nil, errors.New("Wrong format: Inputs must be a valid JSON object.")
+	}
+
+	for name := range patch {
+		if name != "id" && !PatchableFields[name] {
+			return nil, errors.New("Unknown field: " + name)
+		}
+	}
+
+	// Everything looks fine, return the patch document.
+	return patch, nil
+} // End of ValidateInputs function.
+
+// ApplyPatch merges the patch into the device as RFC 7396 describes, and validates the result with the same
+// rules as adding a new device. Changed fields are returned with their new values.
+func ApplyPatch(device types.Device, patch map[string]interface{}) (types.Device, map[string]interface{}, error) {
+	// MergePatch changes the target in place, so the original fields are kept in a copy of it.
+	target, original := map[string]interface{}{}, map[string]interface{}{}
+	originalJson, _ := json.Marshal(device)
+	json.Unmarshal(originalJson, &target)
+	json.Unmarshal(originalJson, &original)
+
+	merged := MergePatch(target, patch).(map[string]interface{})
+	// Removing every attribute leaves an empty object, which is stored as no attributes at all.
+	if attributes, ok := merged["attributes"].(map[string]interface{}); ok && len(attributes) == 0 {
+		delete(merged, "attributes")
+	}
+
+	if merged["id"] != device.ID {
+		return types.Device{}, nil, errors.New("Wrong id: The id of a device can not be changed.")
+	}
+
+	// Decoding the merged document back to the device fails when a field got a value of the wrong type.
+	mergedJson, _ := json.Marshal(merged)
+	PatchedDevice := types.Device{}
+	if err := json.Unmarshal(mergedJson, &PatchedDevice); err != nil {
+		return types.Device{}, nil, errors.New("Wrong format: Patched fields must be strings, and attributes a JSON object.")
+	}
+
+	if err := PatchedDevice.Validate(); err != nil {
+		return types.Device{}, nil, err
+	}
+
+	changes := map[string]interface{}{}
+	for name := range PatchableFields {
+		if !reflect.DeepEqual(merged[name], original[name]) {
+			changes[name] = merged[name]
+		}
+	}
+	// The lookup key of the serial follows the serial.
+	if _, ok := changes["serial"]; ok {
+		changes["serialKey"] = types.NormalizeSerial(PatchedDevice.Serial)
+	}
+
+	return PatchedDevice, changes, nil
+} // End of ApplyPatch function.
+
+// MergePatch implements the MergePatch function of RFC 7396 section 2.
+func MergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = MergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+} // End of MergePatch function.
+
+func main() {
+	lambda.Start(PatchDevice)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"strings"
+	"testing"
+	"types"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked UpdateItem function has received.
+	UpdateInput *dynamodb.UpdateItemInput
+	// Number of times the mocked UpdateItem function has been called.
+	Updates int
+	// The device as the mocked TransactWriteItems function has written it.
+	Written map[string]*dynamodb.AttributeValue
+}
+
+func MockItem() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":          &dynamodb.AttributeValue{S: aws.String("id_test")},
+		"deviceModel": &dynamodb.AttributeValue{S: aws.String("deviceModel_test")},
+		"name":        &dynamodb.AttributeValue{S: aws.String("name_test")},
+		"note":        &dynamodb.AttributeValue{S: aws.String("note_test")},
+		"serial":      &dynamodb.AttributeValue{S: aws.String("serial_test")},
+		"version":     &dynamodb.AttributeValue{N: aws.String("1")},
+	}
+}
+
+// Custom GetItem function for overriding the GetItem of patchDevice.go for using in test scenarios.
+// Every device model but "typo" exists on the mocked DB. A device which has been written in a transaction
+// is read back as it has been written.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	mockOutput := new(dynamodb.GetItemOutput)
+	id := *input.Key["id"].S
+	switch {
+	case *input.TableName == "device_models_test":
+		if id != "typo" {
+			mockOutput.SetItem(map[string]*dynamodb.AttributeValue{"id": input.Key["id"]})
+		}
+	case input.ConsistentRead != nil && *input.ConsistentRead:
+		mockOutput.SetItem(self.Written)
+	case id == "id_test" || id == "busy_test":
+		item := MockItem()
+		item["id"] = input.Key["id"]
+		mockOutput.SetItem(item)
+	}
+	return mockOutput, nil
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of patchDevice.go for using in test scenarios.
+// Mocking UpdateItem output to the stored item with the "note" attribute patched.
+// "busy_test" is changed by someone else every time it is read, so its condition always fails.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	self.UpdateInput = input
+	self.Updates++
+	if *input.Key["id"].S == "busy_test" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	item := MockItem()
+	item["note"] = &dynamodb.AttributeValue{S: aws.String("patched_note")}
+	item["version"] = &dynamodb.AttributeValue{N: aws.String("2")}
+	return &dynamodb.UpdateItemOutput{Attributes: item}, nil
+}
+
+// Custom TransactWriteItems function for overriding the TransactWriteItems of devicemodels.Update for using in test scenarios.
+// The device is written like the mocked UpdateItem does, with the device model which is checked and referenced.
+func (self *MockDynamoDB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	items := input.TransactItems
+	if len(items) != 3 || items[1].ConditionCheck == nil || items[2].Put == nil {
+		return nil, awserr.New("ValidationException", "The device model is not guarded", nil)
+	}
+
+	update := items[0].Update
+	output, err := self.UpdateItem(&dynamodb.UpdateItemInput{Key: update.Key, UpdateExpression: update.UpdateExpression, ConditionExpression: update.ConditionExpression})
+	if err != nil {
+		return nil, err
+	}
+	self.Written = output.Attributes
+	self.Written["deviceModel"] = items[2].Put.Item["deviceModel"]
+	return new(dynamodb.TransactWriteItemsOutput), nil
+}
+
+// Update function in patchDevice.go signature: input: (device types.Device, changes map[string]interface{}, version int64), output: (*dynamodb.UpdateItemOutput, error)
+func TestUpdate(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	_, err := test_aws.Update(types.Device{ID: "id_test"}, map[string]interface{}{"note": "patched_note", "name": "patched_name"}, 1)
+
+	// User input has to end up in attribute names and values only.
+	input := mock.UpdateInput
+	patchedValues := 0
+	for _, value := range input.ExpressionAttributeValues {
+		if value.S != nil && strings.HasPrefix(*value.S, "patched") {
+			patchedValues++
+		}
+	}
+	if err != nil || strings.Contains(*input.UpdateExpression, "patched") || patchedValues != 2 || !strings.Contains(*input.ConditionExpression, "=") || *input.ReturnValues != "ALL_NEW" {
+		t.Errorf("** Updating name and note ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+
+	// Fields which the patch has removed are removed from the item, not set to null.
+	test_aws.Update(types.Device{ID: "id_test"}, map[string]interface{}{"attributes": nil}, 1)
+	if input := mock.UpdateInput; !strings.Contains(*input.UpdateExpression, "REMOVE") {
+		t.Errorf("** Removing attributes ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+} // End of TestUpdate function
+
+// ApplyPatch function in patchDevice.go signature: input: (device types.Device, patch map[string]interface{}), output: (types.Device, map[string]interface{}, error)
+func TestApplyPatchAttributes(t *testing.T) {
+	device := types.Device{ID: "id_test", DeviceModel: "/devicemodels/sensorModel", Name: "name_test", Note: "note_test", Serial: "serial_test",
+		Attributes: map[string]interface{}{"range": 100.0, "channel": "beta"}, Version: 1}
+
+	// Attributes are merged key by key, like any other JSON object.
+	patched, changes, err := ApplyPatch(device, map[string]interface{}{"attributes": map[string]interface{}{"channel": nil}})
+	if err != nil || len(patched.Attributes) != 1 || patched.Attributes["range"] != 100.0 || changes["attributes"] == nil {
+		t.Errorf("** Removing one attribute ** \n \t<resulted device: %v> <resulted changes: %v> <resulted error: %v>", patched, changes, err)
+	}
+
+	// Removing every attribute removes the attributes field.
+	patched, changes, err = ApplyPatch(device, map[string]interface{}{"attributes": map[string]interface{}{"channel": nil, "range": nil}})
+	if removed, ok := changes["attributes"]; err != nil || patched.Attributes != nil || !ok || removed != nil {
+		t.Errorf("** Removing every attribute ** \n \t<resulted device: %v> <resulted changes: %v> <resulted error: %v>", patched, changes, err)
+	}
+
+	_, _, err = ApplyPatch(device, map[string]interface{}{"attributes": "range=1"})
+	if err == nil || err.Error() != "Wrong format: Patched fields must be strings, and attributes a JSON object." {
+		t.Errorf("** Attributes which are not an object ** \n \t<resulted error: %v>", err)
+	}
+} // End of TestApplyPatchAttributes function
+
+// MergePatch function in patchDevice.go signature: input: (target interface{}, patch interface{}), output: (interface{})
+func TestMergePatch(t *testing.T) {
+	target := map[string]interface{}{"a": "b", "c": map[string]interface{}{"d": "e", "f": "g"}}
+	patch := map[string]interface{}{"a": "z", "c": map[string]interface{}{"f": nil}}
+
+	// Test case taken from RFC 7396 section 3.
+	merged := MergePatch(target, patch).(map[string]interface{})
+	nested := merged["c"].(map[string]interface{})
+	if merged["a"] != "z" || nested["d"] != "e" || len(nested) != 1 {
+		t.Errorf("** Merging RFC 7396 example ** \n \t<resulted output: %v>", merged)
+	}
+} // End of TestMergePatch function
+
+// PatchDevice function in patchDevice.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestPatchDevice(t *testing.T) {
+	os.Setenv("DEVICE_MODELS_TABLE_NAME", "device_models_test")
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+	mergePatch := map[string]string{"Content-Type": "application/merge-patch+json"}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Plain JSON content type. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"Content-Type": "application/json"}, Body: "{\"note\":\"patched_note\"}"},
+			ExpectedBody:       "Unsupported media type: Content-Type must be application/merge-patch+json.",
+			ExpectedStatusCode: 415,
+		},
+
+		{
+			Name:               "** Testing: Patch is not an object. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: mergePatch, Body: "[]"},
+			ExpectedBody:       "Wrong format: Inputs must be a valid JSON object.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Unknown field. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: mergePatch, Body: "{\"color\":\"red\"}"},
+			ExpectedBody:       "Unknown field: color",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Device does not exist. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "not_existed"}, Headers: mergePatch, Body: "{\"note\":\"patched_note\"}"},
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Removing a required field. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: mergePatch, Body: "{\"name\":null}"},
+			ExpectedBody:       "Missing field: Name",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Unknown device model. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: mergePatch, Body: "{\"deviceModel\":\"/devicemodels/typo\"}"},
+			ExpectedBody:       "Unknown device model: /devicemodels/typo",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Changing the id. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: mergePatch, Body: "{\"id\":\"other_id\"}"},
+			ExpectedBody:       "Wrong id: The id of a device can not be changed.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Outdated If-Match. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": "\"5\""}, Body: "{\"note\":\"patched_note\"}"},
+			ExpectedBody:       "{\"code\":\"PreconditionFailed\",\"message\":\"The device has been changed since it was read, read it again and retry.\",\"currentVersion\":1}",
+			ExpectedStatusCode: 412,
+		},
+
+		{
+			Name:               "** Testing: Device changed by others on every attempt. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "busy_test"}, Headers: mergePatch, Body: "{\"note\":\"patched_note\"}"},
+			ExpectedBody:       "Conflict: The device is being changed by others, please retry.",
+			ExpectedStatusCode: 409,
+		},
+
+		{
+			Name:               "** Testing: Patching the note. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"content-type": "application/merge-patch+json; charset=utf-8"}, Body: "{\"note\":\"patched_note\"}"},
+			ExpectedBody:       "{\"id\":\"id_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"patched_note\",\"serial\":\"serial_test\",\"version\":2}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Moving to another device model. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: mergePatch, Body: "{\"deviceModel\":\"/devicemodels/model_test\"}"},
+			ExpectedBody:       "{\"id\":\"id_test\",\"deviceModel\":\"/devicemodels/model_test\",\"name\":\"name_test\",\"note\":\"patched_note\",\"serial\":\"serial_test\",\"version\":2}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := PatchDevice(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestPatchDevice function
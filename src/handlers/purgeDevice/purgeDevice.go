@@ -0,0 +1,227 @@
+package main
+
+import (
+	"actor"
+	"clock"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"history"
+	"os"
+	"time"
+	"types"
+	"versioning"
+)
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's TransactWriteItems function inside.
+// The item is removed from DB for good, there is no way back after it. A removed item can not tell who has removed it,
+// so the entry of the purge is written to the history of the device in the same transaction.
+// The item is only removed if it still has the version which it has been read with.
+func (self *AmazonWebServices) Purge(device types.Device, by string, at time.Time) (*dynamodb.TransactWriteItemsOutput, error) {
+	// Only devices which have been deleted before can be purged, so one request can not destroy a device.
+	condition := expression.AttributeExists(expression.Name("deletedAt")).And(versioning.Condition(device.Version))
+
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	// Serialization/Encoding the history entry in "entry" for using in DynamoDB functions.
+	entry, err := dynamodbattribute.MarshalMap(history.NewEntry(device, types.Device{}, at, by))
+	if err != nil {
+		return nil, err
+	}
+
+	// Get desire tables' names from OS's environmental varible.
+	var input = &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Delete: &dynamodb.Delete{
+					TableName: aws.String(os.Getenv("DEVICES_TABLE_NAME")),
+					Key: map[string]*dynamodb.AttributeValue{
+						"id": {
+							S: aws.String(device.ID),
+						},
+					},
+					ConditionExpression:       expr.Condition(),
+					ExpressionAttributeNames:  expr.Names(),
+					ExpressionAttributeValues: expr.Values(),
+				},
+			},
+			{
+				Put: &dynamodb.Put{
+					TableName: aws.String(os.Getenv("HISTORY_TABLE_NAME")),
+					Item:      entry,
+				},
+			},
+		},
+	}
+
+	// Calling either TransactWriteItems function of interface, defined in purgeDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.TransactWriteItems(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside.
+func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	// Calling either GetItem function of interface, defined in purgeDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func PurgeDevice(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which admin has sent through DELETE method.
+	id := request.PathParameters["id"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : id",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// Purging can be made safe by sending the ETag of the device in If-Match header.
+	expectedVersion, checkVersion, err := versioning.IfMatch(request.Headers)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// The whole device is read, as its last fields are kept in the entry of the purge.
+	result, err := TestAws.Get(id)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	CurrentDevice := types.Device{}
+	dynamodbattribute.UnmarshalMap(result.Item, &CurrentDevice)
+
+	// Only deleted devices can be purged, return HTTP error code 404.
+	if len(result.Item) == 0 || CurrentDevice.DeletedAt == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Desired deleted device not found.",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// The device has another version than the client has expected, return HTTP error code 412.
+	if checkVersion && CurrentDevice.Version != expectedVersion {
+		return versioning.PreconditionFailed(CurrentDevice.Version), nil
+	}
+
+	_, err = TestAws.Purge(CurrentDevice, actor.From(request), clock.Now())
+
+	// Reasons are in the order of the writes: the device, then its history entry.
+	if canceled, ok := err.(*dynamodb.TransactionCanceledException); ok && len(canceled.CancellationReasons) > 0 {
+		switch aws.StringValue(canceled.CancellationReasons[0].Code) {
+		// The device has been restored or purged since it was read. Read it again and find out what happened.
+		case "ConditionalCheckFailed":
+			return ConditionFailedResponse(id, checkVersion), nil
+		// Another transaction is writing the device right now, return HTTP error code 409.
+		case "TransactionConflict":
+			return events.APIGatewayProxyResponse{
+				Body:       "Conflict: The device is being changed by others, please retry.",
+				StatusCode: 409,
+			}, nil
+		}
+	}
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Everything looks fine, return HTTP 204 without any body.
+	return events.APIGatewayProxyResponse{
+		StatusCode: 204,
+	}, nil
+} // End of PurgeDevice function
+
+// Tells apart why the condition of the write has failed, by reading the device as it is now.
+func ConditionFailedResponse(id string, checkVersion bool) events.APIGatewayProxyResponse {
+	NotFound := events.APIGatewayProxyResponse{
+		Body:       "Desired deleted device not found.",
+		StatusCode: 404,
+	}
+
+	// Without a version check, the device can only be missing or not deleted, return HTTP error code 404.
+	if !checkVersion {
+		return NotFound
+	}
+
+	result, err := TestAws.Get(id)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}
+	}
+
+	CurrentDevice := types.Device{}
+	dynamodbattribute.UnmarshalMap(result.Item, &CurrentDevice)
+
+	if len(result.Item) == 0 || CurrentDevice.DeletedAt == "" {
+		return NotFound
+	}
+
+	// The device exists but has another version than the client has expected, return HTTP error code 412.
+	return versioning.PreconditionFailed(CurrentDevice.Version)
+} // End of ConditionFailedResponse function
+
+func main() {
+	lambda.Start(PurgeDevice)
+}
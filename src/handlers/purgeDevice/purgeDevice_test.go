@@ -0,0 +1,160 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"testing"
+	"time"
+	"types"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked TransactWriteItems function has received.
+	Input *dynamodb.TransactWriteItemsInput
+}
+
+// Custom TransactWriteItems function for overriding the TransactWriteItems of purgeDevice.go for using in test scenarios.
+// Only "deleted_test" is a deleted device on the mocked DB, other ids fail the condition like on a real DB.
+// "conflict_test" is being written by another transaction and "throttled_test" can not be written at all.
+func (self *MockDynamoDB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	self.Input = input
+	remove := input.TransactItems[0].Delete
+	switch id := *remove.Key["id"].S; {
+	case id == "conflict_test":
+		return nil, Canceled("TransactionConflict")
+	case id == "throttled_test":
+		return nil, Canceled("ThrottlingError")
+	case id != "deleted_test" || OutdatedVersion(remove.ExpressionAttributeValues):
+		return nil, Canceled("ConditionalCheckFailed")
+	}
+	return new(dynamodb.TransactWriteItemsOutput), nil
+}
+
+// Canceled is the error of a transaction canceled by the delete of the device, the history entry has no reason.
+func Canceled(code string) error {
+	return &dynamodb.TransactionCanceledException{
+		Message_: aws.String("Transaction cancelled, please refer cancellation reasons for specific reasons [" + code + ", None]"),
+		CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String(code)},
+			{Code: aws.String("None")},
+		},
+	}
+}
+
+// Custom GetItem function for overriding the GetItem of purgeDevice.go for using in test scenarios.
+// "id_test" is a device, "deleted_test", "conflict_test" and "throttled_test" are deleted devices on the mocked DB, all with version 1.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	MockOutput := new(dynamodb.GetItemOutput)
+	switch *input.Key["id"].S {
+	case "id_test":
+		MockOutput.SetItem(map[string]*dynamodb.AttributeValue{
+			"id":      &dynamodb.AttributeValue{S: aws.String("id_test")},
+			"version": &dynamodb.AttributeValue{N: aws.String("1")},
+		})
+	case "deleted_test", "conflict_test", "throttled_test":
+		MockOutput.SetItem(map[string]*dynamodb.AttributeValue{
+			"id":        &dynamodb.AttributeValue{S: input.Key["id"].S},
+			"deletedAt": &dynamodb.AttributeValue{S: aws.String("2020-01-02T03:04:05Z")},
+			"version":   &dynamodb.AttributeValue{N: aws.String("1")},
+		})
+	}
+	return MockOutput, nil
+}
+
+// Besides the expected version, the only number in the input may be the increment of the version.
+func OutdatedVersion(values map[string]*dynamodb.AttributeValue) bool {
+	for _, value := range values {
+		if value.N != nil && *value.N != "1" {
+			return true
+		}
+	}
+	return false
+}
+
+// Purge function in purgeDevice.go signature: input: (device types.Device, by string, at time.Time), output: (*dynamodb.TransactWriteItemsOutput, error)
+func TestPurge(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	device := types.Device{ID: "deleted_test", Serial: "A1", DeletedAt: "2020-01-02T03:04:05Z", Version: 1}
+	_, err := test_aws.Purge(device, "alice", time.Date(2020, 1, 3, 3, 4, 5, 0, time.UTC))
+
+	// The item is only removed while it's deleted, and the entry of the purge is written with it.
+	remove := mock.Input.TransactItems[0].Delete
+	entry := types.HistoryEntry{}
+	dynamodbattribute.UnmarshalMap(mock.Input.TransactItems[1].Put.Item, &entry)
+	if err != nil || !strings.Contains(*remove.ConditionExpression, "attribute_exists") || entry.Operation != "purge" || entry.ChangedBy != "alice" || entry.Version != 2 || entry.Entry != "2020-01-03T03:04:05Z#00000000000000000002" {
+		t.Errorf("** Purging deleted item ** \n \t<resulted input: \n%s>", mock.Input.GoString())
+	}
+} // End of TestPurge function
+
+// PurgeDevice function in purgeDevice.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestPurgeDevice(t *testing.T) {
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty id input. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": ""}},
+			ExpectedBody:       "Missing field : id",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Device is not deleted. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}},
+			ExpectedBody:       "Desired deleted device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Outdated If-Match. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "deleted_test"}, Headers: map[string]string{"If-Match": "\"5\""}},
+			ExpectedBody:       "{\"code\":\"PreconditionFailed\",\"message\":\"The device has been changed since it was read, read it again and retry.\",\"currentVersion\":1}",
+			ExpectedStatusCode: 412,
+		},
+
+		{
+			Name:               "** Testing: Device is being changed by others. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "conflict_test"}},
+			ExpectedBody:       "Conflict: The device is being changed by others, please retry.",
+			ExpectedStatusCode: 409,
+		},
+
+		{
+			Name:               "** Testing: Throttled purge. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "throttled_test"}},
+			ExpectedBody:       "Internal Server Error\nDatabase error.",
+			ExpectedStatusCode: 500,
+		},
+
+		{
+			Name:               "** Testing: Purging deleted device. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "deleted_test"}},
+			ExpectedBody:       "",
+			ExpectedStatusCode: 204,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := PurgeDevice(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestPurgeDevice function
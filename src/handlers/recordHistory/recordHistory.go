@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"history"
+	"os"
+	"time"
+	"types"
+)
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's PutItem function inside.
+// Entries are keyed by the time and the version of the write, so writing the same entry again leaves it as it is.
+func (self *AmazonWebServices) Put(entry types.HistoryEntry) (*dynamodb.PutItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("HISTORY_TABLE_NAME"))
+
+	// Serialization/Encoding "entry" in "item" for using in DynamoDB functions.
+	item, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: tableName,
+	}
+
+	// Calling either PutItem function of interface, defined in recordHistory_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.PutItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function, with the changes of the devices table.
+// Records of a device arrive in the order they were written. On an error Lambda retries the whole batch,
+// which is safe as writing the same entry twice leaves the history as it is.
+func RecordHistory(event events.DynamoDBEvent) error {
+	for _, record := range event.Records {
+		// A removed item can not tell who has removed it, so purgeDevice writes the entry of a purge itself.
+		if record.EventName == string(events.DynamoDBOperationTypeRemove) {
+			continue
+		}
+
+		entry, written, err := Entry(record)
+		if err == nil && written {
+			_, err = TestAws.Put(entry)
+		}
+		if err != nil {
+			// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+			fmt.Println(fmt.Sprintf("Failed to record history of device %s: %s", record.Change.Keys["id"].String(), err.Error()))
+			return err
+		}
+	}
+	return nil
+} // End of RecordHistory function
+
+// Entry returns the history entry of the write a stream record tells about, and whether the write has to be recorded.
+// Every version is a revision which can be restored, so writes which have not changed any field, i.e: replacing
+// a device with the same fields, are recorded without changes. Only writes keeping the version, i.e: backfilling
+// serial keys, are not recorded.
+func Entry(record events.DynamoDBEventRecord) (types.HistoryEntry, bool, error) {
+	old, err := history.DeviceFromImage(record.Change.OldImage)
+	if err != nil {
+		return types.HistoryEntry{}, false, err
+	}
+	new, err := history.DeviceFromImage(record.Change.NewImage)
+	if err != nil {
+		return types.HistoryEntry{}, false, err
+	}
+
+	// Every write handler stamps the item with who has made the write, and when. Entries are timed like the stamps and
+	// like the entries of purges, not by the stream, so reading a device as of its updatedAt finds its entry.
+	at := record.Change.ApproximateCreationDateTime.Time
+	if updated, err := time.Parse(time.RFC3339, new.UpdatedAt); err == nil {
+		at = updated
+	}
+	entry := history.NewEntry(old, new, at, new.UpdatedBy)
+	return entry, len(entry.Changes) != 0 || old.Version != new.Version, nil
+} // End of Entry function
+
+func main() {
+	lambda.Start(RecordHistory)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strconv"
+	"testing"
+	"time"
+	"types"
+)
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// History entries on the mocked DB, by their keys.
+	Entries map[string]types.HistoryEntry
+	// Makes every PutItem call fail.
+	Failing bool
+}
+
+// Custom PutItem function for overriding the PutItem of recordHistory.go for using in test scenarios.
+func (self *MockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	if self.Failing {
+		return nil, errors.New("Throttled")
+	}
+	entry := types.HistoryEntry{}
+	dynamodbattribute.UnmarshalMap(input.Item, &entry)
+	self.Entries[entry.DeviceID+" "+entry.Entry] = entry
+	return new(dynamodb.PutItemOutput), nil
+}
+
+func Image(device types.Device) map[string]events.DynamoDBAttributeValue {
+	image := map[string]events.DynamoDBAttributeValue{
+		"id":          events.NewStringAttribute(device.ID),
+		"deviceModel": events.NewStringAttribute(device.DeviceModel),
+		"name":        events.NewStringAttribute(device.Name),
+		"note":        events.NewStringAttribute(device.Note),
+		"serial":      events.NewStringAttribute(device.Serial),
+		"version":     events.NewNumberAttribute(strconv.FormatInt(device.Version, 10)),
+		"updatedBy":   events.NewStringAttribute(device.UpdatedBy),
+	}
+	if device.UpdatedAt != "" {
+		image["updatedAt"] = events.NewStringAttribute(device.UpdatedAt)
+	}
+	if device.DeletedAt != "" {
+		image["deletedAt"] = events.NewStringAttribute(device.DeletedAt)
+	}
+	return image
+}
+
+func Record(eventName string, old *types.Device, new *types.Device) events.DynamoDBEventRecord {
+	record := events.DynamoDBEventRecord{EventName: eventName}
+	record.Change.Keys = map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute("id_test")}
+	record.Change.ApproximateCreationDateTime = events.SecondsEpochTime{Time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	if old != nil {
+		record.Change.OldImage = Image(*old)
+	}
+	if new != nil {
+		record.Change.NewImage = Image(*new)
+	}
+	return record
+}
+
+// RecordHistory function in recordHistory.go signature: input: (event events.DynamoDBEvent), output: (error)
+func TestRecordHistory(t *testing.T) {
+	// Entries are timed by the stamps of the writes, the stream records them later.
+	added := types.Device{ID: "id_test", DeviceModel: "/devicemodels/id1", Name: "Sensor", Note: "Testing a sensor.", Serial: "A1", Version: 1, UpdatedAt: "2020-01-02T03:04:01Z", UpdatedBy: "alice"}
+	changed := added
+	changed.Serial, changed.Version, changed.UpdatedAt, changed.UpdatedBy = "A2", 2, "2020-01-02T03:04:02Z", "bob"
+	deleted := changed
+	deleted.DeletedAt, deleted.Version, deleted.UpdatedAt = "2020-01-02T03:04:03Z", 3, "2020-01-02T03:04:03Z"
+	// Devices written before the stamps are timed by the stream.
+	unchanged := deleted
+	unchanged.Version, unchanged.UpdatedAt = 4, ""
+	// Backfilling serial keys writes no new version.
+	backfilled := unchanged
+
+	mock := &MockDynamoDB{Entries: map[string]types.HistoryEntry{}}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+	err := RecordHistory(events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		Record("INSERT", nil, &added),
+		Record("MODIFY", &added, &changed),
+		Record("MODIFY", &changed, &deleted),
+		Record("MODIFY", &deleted, &unchanged),
+		Record("MODIFY", &unchanged, &backfilled),
+		Record("REMOVE", &backfilled, nil),
+	}})
+
+	// Writes without changes are kept with NULL changes on DB.
+	expected := map[string]string{
+		"id_test 2020-01-02T03:04:01Z#00000000000000000001": "{\"version\":1,\"changedAt\":\"2020-01-02T03:04:01Z\",\"changedBy\":\"alice\",\"operation\":\"create\",\"changes\":[{\"field\":\"deviceModel\",\"after\":\"/devicemodels/id1\"},{\"field\":\"name\",\"after\":\"Sensor\"},{\"field\":\"note\",\"after\":\"Testing a sensor.\"},{\"field\":\"serial\",\"after\":\"A1\"}]}",
+		"id_test 2020-01-02T03:04:02Z#00000000000000000002": "{\"version\":2,\"changedAt\":\"2020-01-02T03:04:02Z\",\"changedBy\":\"bob\",\"operation\":\"update\",\"changes\":[{\"field\":\"serial\",\"before\":\"A1\",\"after\":\"A2\"}]}",
+		"id_test 2020-01-02T03:04:03Z#00000000000000000003": "{\"version\":3,\"changedAt\":\"2020-01-02T03:04:03Z\",\"changedBy\":\"bob\",\"operation\":\"delete\",\"changes\":[{\"field\":\"deletedAt\",\"after\":\"2020-01-02T03:04:03Z\"}]}",
+		"id_test 2020-01-02T03:04:05Z#00000000000000000004": "{\"version\":4,\"changedAt\":\"2020-01-02T03:04:05Z\",\"changedBy\":\"bob\",\"operation\":\"update\",\"changes\":null}",
+	}
+	if err != nil || len(mock.Entries) != len(expected) {
+		t.Errorf("** Recording history ** \n \t<expected entries: %d> <resulted entries: %d> <resulted error: %v>", len(expected), len(mock.Entries), err)
+	}
+	for key, body := range expected {
+		entry, _ := json.Marshal(mock.Entries[key])
+		if string(entry) != body {
+			t.Errorf("** Recording history of %s ** \n \t<expected entry: %s> \n \t<resulted entry: %s>", key, body, entry)
+		}
+	}
+
+	// Each entry keeps the whole device as it was written, for reverting to it.
+	if snapshot := mock.Entries["id_test 2020-01-02T03:04:02Z#00000000000000000002"].Device; snapshot == nil || snapshot.Serial != "A2" || snapshot.Version != 2 {
+		t.Errorf("** Recording snapshots ** \n \t<expected serial: A2> <resulted snapshot: %+v>", snapshot)
+	}
+
+	// The whole batch fails, so Lambda retries it.
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{Failing: true}}
+	if err := RecordHistory(events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{Record("INSERT", nil, &added)}}); err == nil {
+		t.Errorf("** Failing to record history ** \n \t<expected an error> <resulted error: nil>")
+	}
+} // End of TestRecordHistory function
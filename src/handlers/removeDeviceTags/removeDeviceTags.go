@@ -0,0 +1,252 @@
+package main
+
+import (
+	"actor"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"os"
+	"strings"
+	"tags"
+	"types"
+	"versioning"
+)
+
+// Number of times tags are removed again, when the device is changed by others while they're being removed.
+const MaxAttempts = 3
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside.
+func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	// Calling either GetItem function of interface, defined in removeDeviceTags_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// The whole tags attribute is written, and removed when no tags are left.
+// The item is only updated if it still has the version which the tags have been removed from.
+func (self *AmazonWebServices) Update(id string, deviceTags map[string]string, by string, version int64) (*dynamodb.UpdateItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	update := expression.Set(expression.Name("tags"), expression.Value(deviceTags))
+	if len(deviceTags) == 0 {
+		update = expression.Remove(expression.Name("tags"))
+	}
+	// Untagging must never create a new item, nor change a deleted one.
+	condition := expression.AttributeExists(expression.Name("id")).
+		And(expression.AttributeNotExists(expression.Name("deletedAt"))).
+		And(versioning.Condition(version))
+
+	expr, err := expression.NewBuilder().WithUpdate(versioning.Increment(actor.Stamp(update, by))).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	}
+
+	// Calling either UpdateItem function of interface, defined in removeDeviceTags_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.UpdateItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func RemoveDeviceTags(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which user has sent through DELETE method.
+	id := request.PathParameters["id"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : id",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// First & foremost we have to validate user input.
+	keys, err := ValidateInputs(request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// Untagging can be made safe by sending the ETag of the device in If-Match header.
+	expectedVersion, checkVersion, err := versioning.IfMatch(request.Headers)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		// The tags are removed from the ones the device has right now.
+		result, err := TestAws.Get(id)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+
+		CurrentDevice := types.Device{}
+		dynamodbattribute.UnmarshalMap(result.Item, &CurrentDevice)
+
+		// Deleted devices can only be restored, not untagged.
+		if len(result.Item) == 0 || CurrentDevice.DeletedAt != "" {
+			return events.APIGatewayProxyResponse{
+				Body:       "Desired device not found.",
+				StatusCode: 404,
+			}, nil
+		}
+
+		// The device has another version than the client has expected, return HTTP error code 412.
+		if checkVersion && CurrentDevice.Version != expectedVersion {
+			return versioning.PreconditionFailed(CurrentDevice.Version), nil
+		}
+
+		// Keys which the device does not have are ignored, as they are already removed.
+		remaining, changed := map[string]string{}, false
+		for key, value := range CurrentDevice.Tags {
+			remaining[key] = value
+		}
+		for _, key := range keys {
+			if _, ok := remaining[key]; ok {
+				delete(remaining, key)
+				changed = true
+			}
+		}
+
+		// Nothing to write, the device does not have the tags.
+		if !changed {
+			jsonResponse, _ := json.Marshal(CurrentDevice)
+			return events.APIGatewayProxyResponse{
+				Body:       string(jsonResponse),
+				Headers:    map[string]string{"ETag": versioning.ETag(CurrentDevice.Version)},
+				StatusCode: 200,
+			}, nil
+		}
+
+		updated, err := TestAws.Update(id, remaining, actor.From(request), CurrentDevice.Version)
+
+		// The device has been changed or deleted since it was read. Read it again and find out what happened.
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			if attempt < MaxAttempts {
+				continue
+			}
+			// Too many writers on the same device, return HTTP error code 409.
+			return events.APIGatewayProxyResponse{
+				Body:       "Conflict: The device is being changed by others, please retry.",
+				StatusCode: 409,
+			}, nil
+		}
+
+		// If internal database errors occurred, return HTTP error code 500.
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+
+		// Return the whole device, as DynamoDB has stored it.
+		UpdatedDevice := types.Device{}
+		dynamodbattribute.UnmarshalMap(updated.Attributes, &UpdatedDevice)
+
+		jsonResponse, _ := json.Marshal(UpdatedDevice)
+		return events.APIGatewayProxyResponse{
+			Body:    string(jsonResponse),
+			Headers: map[string]string{"ETag": versioning.ETag(UpdatedDevice.Version)},
+			// Everything looks fine, return HTTP 200
+			StatusCode: 200,
+		}, nil
+	}
+} // End of RemoveDeviceTags function
+
+func ValidateInputs(request events.APIGatewayProxyRequest) ([]string, error) {
+	text, ok := request.QueryStringParameters["keys"]
+	if !ok {
+		return nil, errors.New("Missing field: keys, i.e: keys=site,env.")
+	}
+
+	keys := []string{}
+	for _, key := range strings.Split(text, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, errors.New("Wrong format: keys can not have an empty key, i.e: keys=site,env.")
+		}
+		if err := tags.ValidateKey(key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	// Everything looks fine, return the keys of the tags to remove.
+	return keys, nil
+} // End of ValidateInputs function.
+
+func main() {
+	lambda.Start(RemoveDeviceTags)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked UpdateItem function has received.
+	UpdateInput *dynamodb.UpdateItemInput
+	// Number of times the mocked UpdateItem function has been called.
+	Updates int
+}
+
+// The stored device, tagged with site=berlin and env=lab.
+func MockItem() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":          &dynamodb.AttributeValue{S: aws.String("id_test")},
+		"deviceModel": &dynamodb.AttributeValue{S: aws.String("deviceModel_test")},
+		"name":        &dynamodb.AttributeValue{S: aws.String("name_test")},
+		"note":        &dynamodb.AttributeValue{S: aws.String("note_test")},
+		"serial":      &dynamodb.AttributeValue{S: aws.String("serial_test")},
+		"tags":        &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{"site": {S: aws.String("berlin")}, "env": {S: aws.String("lab")}}},
+		"version":     &dynamodb.AttributeValue{N: aws.String("1")},
+	}
+}
+
+// Custom GetItem function for overriding the GetItem of removeDeviceTags.go for using in test scenarios.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	mockOutput := new(dynamodb.GetItemOutput)
+	if id := *input.Key["id"].S; id == "id_test" || id == "busy_test" {
+		mockOutput.SetItem(MockItem())
+	}
+	return mockOutput, nil
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of removeDeviceTags.go for using in test scenarios.
+// Mocking UpdateItem output to the stored item with the tags of the update, without tags when they are removed.
+// "busy_test" is changed by someone else every time it is read, so its condition always fails.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	self.UpdateInput = input
+	self.Updates++
+	if *input.Key["id"].S == "busy_test" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	item := MockItem()
+	delete(item, "tags")
+	for _, value := range input.ExpressionAttributeValues {
+		if value.M != nil {
+			item["tags"] = value
+		}
+	}
+	item["version"] = &dynamodb.AttributeValue{N: aws.String("2")}
+	return &dynamodb.UpdateItemOutput{Attributes: item}, nil
+}
+
+// Update function in removeDeviceTags.go signature: input: (id string, deviceTags map[string]string, by string, version int64), output: (*dynamodb.UpdateItemOutput, error)
+func TestUpdate(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	// The tags attribute is removed with the last tag.
+	_, err := test_aws.Update("id_test", map[string]string{}, "alice", 1)
+	input := mock.UpdateInput
+	if err != nil || !strings.Contains(*input.UpdateExpression, "REMOVE") || !strings.Contains(*input.ConditionExpression, "=") || *input.ReturnValues != "ALL_NEW" {
+		t.Errorf("** Removing the last tag ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+
+	_, err = test_aws.Update("id_test", map[string]string{"site": "berlin"}, "alice", 1)
+	input = mock.UpdateInput
+	if err != nil || !strings.Contains(*input.UpdateExpression, "SET") || strings.Contains(*input.UpdateExpression, "REMOVE") {
+		t.Errorf("** Removing a tag ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+} // End of TestUpdate function
+
+// RemoveDeviceTags function in removeDeviceTags.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestRemoveDeviceTags(t *testing.T) {
+	TestCases := []TestCase{
+		{
+			Name:               "** Testing: Missing id. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"keys": "env"}},
+			ExpectedBody:       "Missing field : id",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Missing keys. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}},
+			ExpectedBody:       "Missing field: keys, i.e: keys=site,env.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Empty key. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, QueryStringParameters: map[string]string{"keys": "env,"}},
+			ExpectedBody:       "Wrong format: keys can not have an empty key, i.e: keys=site,env.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Device not found. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "missing_test"}, QueryStringParameters: map[string]string{"keys": "env"}},
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Stale If-Match. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"If-Match": "\"7\""}, QueryStringParameters: map[string]string{"keys": "env"}},
+			ExpectedBody:       "{\"code\":\"PreconditionFailed\",\"message\":\"The device has been changed since it was read, read it again and retry.\",\"currentVersion\":1}",
+			ExpectedStatusCode: 412,
+		},
+
+		{
+			Name:               "** Testing: Removing a tag. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"If-Match": "\"1\""}, QueryStringParameters: map[string]string{"keys": "env,color"}},
+			ExpectedBody:       "{\"id\":\"id_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\",\"tags\":{\"site\":\"berlin\"},\"version\":2}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Removing every tag. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, QueryStringParameters: map[string]string{"keys": "env, site"}},
+			ExpectedBody:       "{\"id\":\"id_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\",\"version\":2}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Device is being changed by others. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "busy_test"}, QueryStringParameters: map[string]string{"keys": "env"}},
+			ExpectedBody:       "Conflict: The device is being changed by others, please retry.",
+			ExpectedStatusCode: 409,
+		},
+	}
+
+	for _, test := range TestCases {
+		// Executing each test cases scenario.
+		TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+		response, _ := RemoveDeviceTags(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// Removing a tag the device does not have does not write anything.
+	mock := &MockDynamoDB{}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+	response, _ := RemoveDeviceTags(events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, QueryStringParameters: map[string]string{"keys": "color"}})
+	if response.StatusCode != 200 || mock.Updates != 0 || response.Headers["ETag"] != "\"1\"" {
+		t.Errorf("** Testing: Removing a missing tag. ** \n \t<resulted error-code: %d> <resulted updates: %d> <resulted ETag: %s>", response.StatusCode, mock.Updates, response.Headers["ETag"])
+	}
+} // End of TestRemoveDeviceTags function
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"actor"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"os"
+	"types"
+	"versioning"
+)
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// Removing the deletion mark makes the device visible to clients again.
+// If checkVersion is true, the device is only restored when its stored version is expectedVersion.
+func (self *AmazonWebServices) Restore(id string, by string, expectedVersion int64, checkVersion bool) (*dynamodb.UpdateItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	update := actor.Stamp(expression.Remove(expression.Name("deletedAt")), by)
+	// Only deleted devices can be restored, this also makes sure no new item is created.
+	condition := expression.AttributeExists(expression.Name("deletedAt"))
+	if checkVersion {
+		condition = condition.And(versioning.Condition(expectedVersion))
+	}
+
+	expr, err := expression.NewBuilder().WithUpdate(versioning.Increment(update)).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	}
+
+	// Calling either UpdateItem function of interface, defined in restoreDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.UpdateItem(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside.
+func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	// Calling either GetItem function of interface, defined in restoreDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func RestoreDevice(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which admin has sent through POST method.
+	id := request.PathParameters["id"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : id",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// Restoring can be made safe by sending the ETag of the device in If-Match header.
+	expectedVersion, checkVersion, err := versioning.IfMatch(request.Headers)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	result, err := TestAws.Restore(id, actor.From(request), expectedVersion, checkVersion)
+
+	// The condition fails if there is no deleted device with this id or it has another version.
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ConditionFailedResponse(id, checkVersion), nil
+	}
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Deserialization/Decoding "result.Attributes" to Go struct.
+	RestoredDevice := types.Device{}
+	dynamodbattribute.UnmarshalMap(result.Attributes, &RestoredDevice)
+
+	// Serialization/Encoding "RestoredDevice" to JSON.
+	jsonResponse, _ := json.Marshal(RestoredDevice)
+	return events.APIGatewayProxyResponse{
+		Body:    string(jsonResponse),
+		Headers: map[string]string{"ETag": versioning.ETag(RestoredDevice.Version)},
+		// Everything looks fine, return HTTP 200
+		StatusCode: 200,
+	}, nil
+} // End of RestoreDevice function
+
+// Tells apart why the condition of the write has failed, by reading the device as it is now.
+func ConditionFailedResponse(id string, checkVersion bool) events.APIGatewayProxyResponse {
+	NotFound := events.APIGatewayProxyResponse{
+		Body:       "Desired deleted device not found.",
+		StatusCode: 404,
+	}
+
+	// Without a version check, the device can only be missing or not deleted, return HTTP error code 404.
+	if !checkVersion {
+		return NotFound
+	}
+
+	result, err := TestAws.Get(id)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}
+	}
+
+	CurrentDevice := types.Device{}
+	dynamodbattribute.UnmarshalMap(result.Item, &CurrentDevice)
+
+	if len(result.Item) == 0 || CurrentDevice.DeletedAt == "" {
+		return NotFound
+	}
+
+	// The device exists but has another version than the client has expected, return HTTP error code 412.
+	return versioning.PreconditionFailed(CurrentDevice.Version)
+} // End of ConditionFailedResponse function
+
+func main() {
+	lambda.Start(RestoreDevice)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked UpdateItem function has received.
+	Input *dynamodb.UpdateItemInput
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of restoreDevice.go for using in test scenarios.
+// Only "deleted_test" is a deleted device on the mocked DB, other ids fail the condition like on a real DB.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	self.Input = input
+	if *input.Key["id"].S != "deleted_test" || OutdatedVersion(input.ExpressionAttributeValues) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+
+	mockOutput := new(dynamodb.UpdateItemOutput)
+	mockOutput.SetAttributes(
+		map[string]*dynamodb.AttributeValue{
+			"id":          &dynamodb.AttributeValue{S: aws.String("deleted_test")},
+			"deviceModel": &dynamodb.AttributeValue{S: aws.String("deviceModel_test")},
+			"name":        &dynamodb.AttributeValue{S: aws.String("name_test")},
+			"note":        &dynamodb.AttributeValue{S: aws.String("note_test")},
+			"serial":      &dynamodb.AttributeValue{S: aws.String("serial_test")},
+		},
+	)
+	return mockOutput, nil
+}
+
+// Custom GetItem function for overriding the GetItem of restoreDevice.go for using in test scenarios.
+// "id_test" is a device and "deleted_test" a deleted device on the mocked DB, both with version 1.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	MockOutput := new(dynamodb.GetItemOutput)
+	switch *input.Key["id"].S {
+	case "id_test":
+		MockOutput.SetItem(map[string]*dynamodb.AttributeValue{
+			"id":      &dynamodb.AttributeValue{S: aws.String("id_test")},
+			"version": &dynamodb.AttributeValue{N: aws.String("1")},
+		})
+	case "deleted_test":
+		MockOutput.SetItem(map[string]*dynamodb.AttributeValue{
+			"id":        &dynamodb.AttributeValue{S: aws.String("deleted_test")},
+			"deletedAt": &dynamodb.AttributeValue{S: aws.String("2020-01-02T03:04:05Z")},
+			"version":   &dynamodb.AttributeValue{N: aws.String("1")},
+		})
+	}
+	return MockOutput, nil
+}
+
+// Besides the expected version, the only number in the input may be the increment of the version.
+func OutdatedVersion(values map[string]*dynamodb.AttributeValue) bool {
+	for _, value := range values {
+		if value.N != nil && *value.N != "1" {
+			return true
+		}
+	}
+	return false
+}
+
+// Restore function in restoreDevice.go signature: input: (id string, by string, expectedVersion int64, checkVersion bool), output: (*dynamodb.UpdateItemOutput, error)
+func TestRestore(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	_, err := test_aws.Restore("deleted_test", "alice", 1, true)
+
+	if err != nil || !strings.Contains(*mock.Input.UpdateExpression, "REMOVE") || !strings.Contains(*mock.Input.ConditionExpression, "attribute_exists") {
+		t.Errorf("** Restoring deleted item ** \n \t<resulted input: \n%s>", mock.Input.GoString())
+	}
+} // End of TestRestore function
+
+// RestoreDevice function in restoreDevice.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestRestoreDevice(t *testing.T) {
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty id input. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": ""}},
+			ExpectedBody:       "Missing field : id",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Device is not deleted. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}},
+			ExpectedBody:       "Desired deleted device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Outdated If-Match. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "deleted_test"}, Headers: map[string]string{"If-Match": "\"5\""}},
+			ExpectedBody:       "{\"code\":\"PreconditionFailed\",\"message\":\"The device has been changed since it was read, read it again and retry.\",\"currentVersion\":1}",
+			ExpectedStatusCode: 412,
+		},
+
+		{
+			Name:               "** Testing: If-Match of a device which is not deleted. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"If-Match": "\"1\""}},
+			ExpectedBody:       "Desired deleted device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Restoring deleted device. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "deleted_test"}},
+			ExpectedBody:       "{\"id\":\"deleted_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\"}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := RestoreDevice(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestRestoreDevice function
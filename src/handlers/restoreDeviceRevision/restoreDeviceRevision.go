@@ -0,0 +1,353 @@
+package main
+
+import (
+	"actor"
+	"devicemodels"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"history"
+	"os"
+	"strconv"
+	"strings"
+	"types"
+	"versioning"
+)
+
+// Number of times a revert is tried again, when the device is changed by others while it's being reverted.
+const MaxAttempts = 3
+
+// The only action on a revision, which follows its number in the path, i.e: /devices/id1/revisions/3:restore.
+const RestoreAction = ":restore"
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside.
+func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	// Calling either GetItem function of interface, defined in restoreDeviceRevision_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's Query function inside.
+// Reads the history of the device backwards, only the entries of the revision and the purges which end former lives of the device.
+func (self *AmazonWebServices) Revisions(id string, revision int64, startKey map[string]*dynamodb.AttributeValue) (*dynamodb.QueryOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("HISTORY_TABLE_NAME"))
+
+	keyCondition := expression.Key("id").Equal(expression.Value(id))
+	filter := expression.Name("version").Equal(expression.Value(revision)).
+		Or(expression.Name("operation").Equal(expression.Value(history.Purge)))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(filter).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.QueryInput{
+		TableName:                 tableName,
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(false),
+	}
+	// Continue right after the last entry of the previous page.
+	if len(startKey) != 0 {
+		input.ExclusiveStartKey = startKey
+	}
+
+	// Calling either Query function of interface, defined in restoreDeviceRevision_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Query(input)
+	return result, err
+}
+
+// Revision finds the snapshot of the revision of the device, among the entries of its current life.
+// A device which has been purged and added again starts from the first revision, its former revisions can not be restored.
+func (self *AmazonWebServices) Revision(id string, revision int64) (*types.Device, error) {
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		result, err := self.Revisions(id, revision, startKey)
+		if err != nil {
+			return nil, err
+		}
+
+		entries := []types.HistoryEntry{}
+		if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &entries); err != nil {
+			return nil, err
+		}
+		// Entries come newest first, so a purge ends the current life of the device.
+		if len(entries) != 0 {
+			if entries[0].Operation == history.Purge {
+				return nil, nil
+			}
+			return entries[0].Device, nil
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return nil, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// The item is only updated if it has not been deleted and still has the version which it has been read with.
+// When the revision had another device model, the model is checked in the same transaction, see devicemodels.Update.
+func (self *AmazonWebServices) Revert(device types.Device, version int64, modelChanged bool) (*dynamodb.UpdateItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	update := expression.Set(expression.Name("deviceModel"), expression.Value(device.DeviceModel)).
+		Set(expression.Name("name"), expression.Value(device.Name)).
+		Set(expression.Name("note"), expression.Value(device.Note)).
+		Set(expression.Name("serial"), expression.Value(device.Serial)).
+		Set(expression.Name("serialKey"), expression.Value(types.NormalizeSerial(device.Serial)))
+	// Tags and attributes which the revision did not have are removed.
+	if len(device.Tags) != 0 {
+		update = update.Set(expression.Name("tags"), expression.Value(device.Tags))
+	} else {
+		update = update.Remove(expression.Name("tags"))
+	}
+	if len(device.Attributes) != 0 {
+		update = update.Set(expression.Name("attributes"), expression.Value(device.Attributes))
+	} else {
+		update = update.Remove(expression.Name("attributes"))
+	}
+	condition := expression.AttributeExists(expression.Name("id")).
+		And(expression.AttributeNotExists(expression.Name("deletedAt"))).
+		And(versioning.Condition(version))
+
+	expr, err := expression.NewBuilder().WithUpdate(versioning.Increment(actor.Stamp(update, device.UpdatedBy))).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(device.ID),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	}
+
+	// Calling either UpdateItem or TransactWriteItems function of interface, defined in restoreDeviceRevision_test.go file, or api with the input we've provided.
+	result, err := devicemodels.Update(self.DynamoDB, input, device, modelChanged)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func RestoreDeviceRevision(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which user has sent through POST method.
+	id := request.PathParameters["id"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : id",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// First & foremost we have to validate user input.
+	revision, err := ValidateInputs(request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// Reverts can be made safe by sending the ETag of the device in If-Match header.
+	expectedVersion, checkVersion, err := versioning.IfMatch(request.Headers)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	Snapshot, err := TestAws.Revision(id, revision)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// If the device never had this revision, it has been recorded before snapshots were kept, or it is not recorded yet, return HTTP error code 404.
+	if Snapshot == nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Desired revision not found, it may not be recorded yet, as history is recorded shortly after each write.",
+			StatusCode: 404,
+		}, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		// The revert is compared against the device as it is right now.
+		result, err := TestAws.Get(id)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+
+		CurrentDevice := types.Device{}
+		dynamodbattribute.UnmarshalMap(result.Item, &CurrentDevice)
+
+		// Deleted devices can only be restored, not reverted.
+		if len(result.Item) == 0 || CurrentDevice.DeletedAt != "" {
+			return events.APIGatewayProxyResponse{
+				Body:       "Desired device not found.",
+				StatusCode: 404,
+			}, nil
+		}
+
+		// The device has another version than the client has expected, return HTTP error code 412.
+		if checkVersion && CurrentDevice.Version != expectedVersion {
+			return versioning.PreconditionFailed(CurrentDevice.Version), nil
+		}
+
+		RevertedDevice := history.Revert(CurrentDevice, *Snapshot)
+		changes := history.Diff(CurrentDevice, RevertedDevice)
+
+		// The device already is as the revision was, nothing to write.
+		if len(changes) == 0 {
+			return RevertResponse(revision, CurrentDevice.Version, changes), nil
+		}
+
+		// The device model of the revision may have been deleted or changed since, the device has to follow it as it is now.
+		if err = devicemodels.Check(TestAws.DynamoDB, RevertedDevice); err != nil {
+			if devicemodels.IsClientError(err) {
+				return events.APIGatewayProxyResponse{
+					Body:       err.Error(),
+					StatusCode: 400,
+				}, nil
+			}
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+
+		// Stamped with when and by whom it has been reverted, for auditing and the history of the device.
+		RevertedDevice.UpdatedBy = actor.From(request)
+		updated, err := TestAws.Revert(RevertedDevice, CurrentDevice.Version, RevertedDevice.DeviceModel != CurrentDevice.DeviceModel)
+
+		// The device has been changed or deleted since it was read. Read it again and find out what happened.
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			if attempt < MaxAttempts {
+				continue
+			}
+			// Too many writers on the same device, return HTTP error code 409.
+			return events.APIGatewayProxyResponse{
+				Body:       "Conflict: The device is being changed by others, please retry.",
+				StatusCode: 409,
+			}, nil
+		}
+
+		// The device model has been deleted since it was checked, or is being deleted. Return HTTP error code 400.
+		if devicemodels.IsClientError(err) {
+			return events.APIGatewayProxyResponse{
+				Body:       err.Error(),
+				StatusCode: 400,
+			}, nil
+		}
+
+		// If internal database errors occurred, return HTTP error code 500.
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+
+		UpdatedDevice := types.Device{}
+		dynamodbattribute.UnmarshalMap(updated.Attributes, &UpdatedDevice)
+
+		return RevertResponse(revision, UpdatedDevice.Version, history.Diff(CurrentDevice, UpdatedDevice)), nil
+	}
+} // End of RestoreDeviceRevision function
+
+// Tells what the revert has changed, with the version of the device in ETag header.
+func RevertResponse(revision int64, version int64, changes []types.Change) events.APIGatewayProxyResponse {
+	jsonResponse, _ := json.Marshal(types.Revert{Revision: revision, Version: version, Changes: changes})
+	return events.APIGatewayProxyResponse{
+		Body:    string(jsonResponse),
+		Headers: map[string]string{"ETag": versioning.ETag(version)},
+		// Everything looks fine, return HTTP 200
+		StatusCode: 200,
+	}
+} // End of RevertResponse function
+
+func ValidateInputs(request events.APIGatewayProxyRequest) (int64, error) {
+	// API Gateway can not match a part of a path segment, so the action comes along with the number, i.e: "3:restore".
+	segment := request.PathParameters["revision"]
+	if !strings.HasSuffix(segment, RestoreAction) {
+		return 0, errors.New("Unknown action: revisions can only be restored, i.e: /devices/<id>/revisions/3:restore")
+	}
+
+	revision, err := strconv.ParseInt(strings.TrimSuffix(segment, RestoreAction), 10, 64)
+	if err != nil || revision < 1 {
+		return 0, errors.New("Wrong format: revision must be a positive number.")
+	}
+
+	// Everything looks fine, return the number of the revision.
+	return revision, nil
+} // End of ValidateInputs function.
+
+func main() {
+	lambda.Start(RestoreDeviceRevision)
+}
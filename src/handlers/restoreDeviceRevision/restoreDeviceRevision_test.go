@@ -0,0 +1,242 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"types"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked UpdateItem function has received.
+	UpdateInput *dynamodb.UpdateItemInput
+}
+
+// The device as it is now, at its third revision.
+func MockDevice(id string) types.Device {
+	return types.Device{ID: id, DeviceModel: "/devicemodels/model_test", Name: "Sensor B", Note: "Testing a sensor.", Serial: "A2", Tags: map[string]string{"site": "paris"}, Status: "active", Version: 3}
+}
+
+// The history of each device, newest entries first.
+// "reborn_test" has been purged at its third revision and added again. "legacy_test" has been recorded before snapshots were kept.
+func MockHistory(id string) []types.HistoryEntry {
+	first := MockDevice(id)
+	first.Name, first.Serial, first.Tags, first.Version = "Sensor", "A1", map[string]string{"site": "berlin"}, 1
+	moved := MockDevice(id)
+	moved.DeviceModel, moved.Version = "/devicemodels/gone_test", 2
+	current := MockDevice(id)
+
+	switch id {
+	case "reborn_test":
+		return []types.HistoryEntry{
+			{DeviceID: id, Entry: "3", Version: 1, Operation: "create", Device: &first},
+			{DeviceID: id, Entry: "2", Version: 3, Operation: "purge"},
+			{DeviceID: id, Entry: "1", Version: 2, Operation: "update", Device: &moved},
+		}
+	case "legacy_test":
+		return []types.HistoryEntry{{DeviceID: id, Entry: "1", Version: 1, Operation: "create"}}
+	}
+	return []types.HistoryEntry{
+		{DeviceID: id, Entry: "3", Version: 3, Operation: "update", Device: &current},
+		{DeviceID: id, Entry: "2", Version: 2, Operation: "update", Device: &moved},
+		{DeviceID: id, Entry: "1", Version: 1, Operation: "create", Device: &first},
+	}
+}
+
+// Custom GetItem function for overriding the GetItem of restoreDeviceRevision.go for using in test scenarios.
+// Only "model_test" exists on the mocked device models table, "gone_test" has been deleted.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	mockOutput := new(dynamodb.GetItemOutput)
+	id := *input.Key["id"].S
+	if *input.TableName == "device_models_test" {
+		if id == "model_test" {
+			mockOutput.SetItem(map[string]*dynamodb.AttributeValue{"id": input.Key["id"]})
+		}
+		return mockOutput, nil
+	}
+	if id != "missing_test" {
+		mockOutput.Item, _ = dynamodbattribute.MarshalMap(MockDevice(id))
+	}
+	return mockOutput, nil
+}
+
+// Custom Query function for overriding the Query of restoreDeviceRevision.go for using in test scenarios.
+// Mocking Query output to pages of a single entry, which are empty when the entry does not pass the filter.
+func (self *MockDynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	mockOutput := new(dynamodb.QueryOutput)
+	// The only number is the revision, and the only string but the purge operation is the id.
+	var entries []types.HistoryEntry
+	var revision int64
+	for _, value := range input.ExpressionAttributeValues {
+		if value.N != nil {
+			revision, _ = strconv.ParseInt(*value.N, 10, 64)
+		} else if *value.S != "purge" {
+			entries = MockHistory(*value.S)
+		}
+	}
+
+	index := 0
+	if input.ExclusiveStartKey != nil {
+		index, _ = strconv.Atoi(*input.ExclusiveStartKey["index"].N)
+	}
+	if entry := entries[index]; entry.Version == revision || entry.Operation == "purge" {
+		item, _ := dynamodbattribute.MarshalMap(entry)
+		mockOutput.SetItems([]map[string]*dynamodb.AttributeValue{item})
+	}
+	if index+1 < len(entries) {
+		mockOutput.SetLastEvaluatedKey(map[string]*dynamodb.AttributeValue{"index": {N: aws.String(strconv.Itoa(index + 1))}})
+	}
+	return mockOutput, nil
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of restoreDeviceRevision.go for using in test scenarios.
+// Mocking UpdateItem output to the stored device with the values which the update expression sets, at its next version.
+// "busy_test" is changed by someone else every time it is read, so its condition always fails.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	self.UpdateInput = input
+	if *input.Key["id"].S == "busy_test" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	item, _ := dynamodbattribute.MarshalMap(MockDevice(*input.Key["id"].S))
+	for placeholder, name := range input.ExpressionAttributeNames {
+		for key, value := range input.ExpressionAttributeValues {
+			if strings.Contains(*input.UpdateExpression, placeholder+" = "+key) {
+				item[*name] = value
+			}
+		}
+	}
+	item["version"] = &dynamodb.AttributeValue{N: aws.String("4")}
+	return &dynamodb.UpdateItemOutput{Attributes: item}, nil
+}
+
+// Revert function in restoreDeviceRevision.go signature: input: (device types.Device, version int64, modelChanged bool), output: (*dynamodb.UpdateItemOutput, error)
+func TestRevert(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	// Tags and attributes which the revision did not have are removed, and the device is only written at the version it has been read with.
+	device := MockDevice("id_test")
+	device.Tags, device.UpdatedBy = nil, "alice"
+	_, err := test_aws.Revert(device, 3, false)
+	input := mock.UpdateInput
+	if err != nil || !strings.Contains(*input.UpdateExpression, "REMOVE") || !strings.Contains(*input.ConditionExpression, "attribute_not_exists") || !strings.Contains(input.GoString(), "\"alice\"") {
+		t.Errorf("** Reverting a device ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+} // End of TestRevert function
+
+// RestoreDeviceRevision function in restoreDeviceRevision.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestRestoreDeviceRevision(t *testing.T) {
+	os.Setenv("DEVICE_MODELS_TABLE_NAME", "device_models_test")
+
+	TestCases := []TestCase{
+		{
+			Name:               "** Testing: Missing id. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"revision": "1:restore"}},
+			ExpectedBody:       "Missing field : id",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Unknown action. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test", "revision": "1:delete"}},
+			ExpectedBody:       "Unknown action: revisions can only be restored, i.e: /devices/<id>/revisions/3:restore",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Wrong revision. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test", "revision": "0:restore"}},
+			ExpectedBody:       "Wrong format: revision must be a positive number.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Revision not found. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test", "revision": "7:restore"}},
+			ExpectedBody:       "Desired revision not found, it may not be recorded yet, as history is recorded shortly after each write.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Revision of a former life of the device. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "reborn_test", "revision": "2:restore"}},
+			ExpectedBody:       "Desired revision not found, it may not be recorded yet, as history is recorded shortly after each write.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Revision without snapshot. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "legacy_test", "revision": "1:restore"}},
+			ExpectedBody:       "Desired revision not found, it may not be recorded yet, as history is recorded shortly after each write.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Device not found. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "missing_test", "revision": "1:restore"}},
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Stale If-Match. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test", "revision": "1:restore"}, Headers: map[string]string{"If-Match": "\"2\""}},
+			ExpectedBody:       "{\"code\":\"PreconditionFailed\",\"message\":\"The device has been changed since it was read, read it again and retry.\",\"currentVersion\":3}",
+			ExpectedStatusCode: 412,
+		},
+
+		{
+			Name:               "** Testing: Device model of the revision has been deleted. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test", "revision": "2:restore"}},
+			ExpectedBody:       "Unknown device model: /devicemodels/gone_test",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Reverting a device. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test", "revision": "1:restore"}, Headers: map[string]string{"If-Match": "\"3\""}},
+			ExpectedBody:       "{\"revision\":1,\"version\":4,\"changes\":[{\"field\":\"name\",\"before\":\"Sensor B\",\"after\":\"Sensor\"},{\"field\":\"serial\",\"before\":\"A2\",\"after\":\"A1\"},{\"field\":\"tags\",\"before\":{\"site\":\"paris\"},\"after\":{\"site\":\"berlin\"}}]}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Reverting to the current revision. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test", "revision": "3:restore"}},
+			ExpectedBody:       "{\"revision\":3,\"version\":3,\"changes\":[]}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Device is being changed by others. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "busy_test", "revision": "1:restore"}},
+			ExpectedBody:       "Conflict: The device is being changed by others, please retry.",
+			ExpectedStatusCode: 409,
+		},
+	}
+
+	for _, test := range TestCases {
+		// Executing each test cases scenario.
+		TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+		response, _ := RestoreDeviceRevision(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestRestoreDeviceRevision function
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"clock"
+	"encoding/csv"
+	"encoding/json"
+	"fields"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"tags"
+	"types"
+)
+
+// Number of segments of the table which are scanned at the same time.
+const TotalSegments = 4
+
+// Formats an export can be written in.
+const (
+	CSVFormat    = "csv"
+	NDJSONFormat = "ndjson"
+)
+
+// Media types of the exported files, by their format.
+var MediaTypes = map[string]string{
+	CSVFormat:    "text/csv",
+	NDJSONFormat: "application/x-ndjson",
+}
+
+// Columns of a CSV export, in their order. Deleted devices are exported as well, with the time they were deleted.
+var Columns = append(append([]string{}, fields.DeviceFields...), "deletedAt")
+
+// Encoder writes devices one by one in the format of the export.
+type Encoder interface {
+	Encode(device types.Device) error
+	// Flush writes what is still buffered, after the last device.
+	Flush() error
+}
+
+// CSVEncoder writes a header row, then a row for each device with its fields in the order of Columns.
+// Values are quoted only when they need it, the same value is always written the same way.
+// Tags are written as a selector would match them, i.e: "env=lab,site=berlin", sorted by key.
+type CSVEncoder struct {
+	writer *csv.Writer
+}
+
+// NDJSONEncoder writes each device as a JSON object on its own line, with the fields in the order of types.Device.
+type NDJSONEncoder struct {
+	encoder *json.Encoder
+}
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+	Uploader s3manageriface.UploaderAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+		var s3svc *s3.S3 = s3.New(Aws.Session)
+		Aws.Uploader = s3manager.NewUploaderWithClient(s3svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+func NewEncoder(format string, writer io.Writer) (Encoder, error) {
+	if format == NDJSONFormat {
+		encoder := json.NewEncoder(writer)
+		// Notes are written as they are, i.e: "<" instead of "\u003c".
+		encoder.SetEscapeHTML(false)
+		return &NDJSONEncoder{encoder: encoder}, nil
+	}
+
+	encoder := &CSVEncoder{writer: csv.NewWriter(writer)}
+	return encoder, encoder.writer.Write(Columns)
+}
+
+func (self *CSVEncoder) Encode(device types.Device) error {
+	values, err := fields.Select(device, Columns)
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, len(Columns))
+	for index, column := range Columns {
+		value, ok := values[column]
+		if !ok {
+			// Empty fields which the device omits are empty cells.
+			continue
+		}
+		if column == "tags" {
+			pairs := []string{}
+			for _, key := range tags.SortedKeys(device.Tags) {
+				pairs = append(pairs, key+"="+device.Tags[key])
+			}
+			record[index] = strings.Join(pairs, ",")
+			continue
+		}
+		// Strings are written without their JSON quotes, anything else as it's encoded in JSON.
+		if err := json.Unmarshal(value, &record[index]); err != nil {
+			record[index] = string(value)
+		}
+	}
+	return self.writer.Write(record)
+}
+
+func (self *CSVEncoder) Flush() error {
+	self.writer.Flush()
+	return self.writer.Error()
+}
+
+func (self *NDJSONEncoder) Encode(device types.Device) error {
+	return self.encoder.Encode(device)
+}
+
+func (self *NDJSONEncoder) Flush() error {
+	return nil
+}
+
+// Preparing DynamoDB Session and Calling DB's Scan function inside, for one page of one segment of the table.
+func (self *AmazonWebServices) ScanSegment(segment int64, startKey map[string]*dynamodb.AttributeValue) (*dynamodb.ScanOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	// An audit needs every device as it has been written, deleted devices included.
+	var input = &dynamodb.ScanInput{
+		TableName:      tableName,
+		Segment:        aws.Int64(segment),
+		TotalSegments:  aws.Int64(TotalSegments),
+		ConsistentRead: aws.Bool(true),
+	}
+	// Continue right after the last item of the previous page.
+	if len(startKey) != 0 {
+		input.ExclusiveStartKey = startKey
+	}
+
+	// Calling either Scan function of interface, defined in exportDevices_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.Scan(input)
+	return result, err
+}
+
+// Export scans all segments of the table at the same time, and encodes each page as soon as it arrives.
+// Only a few pages are held in memory at once, whatever the size of the table is.
+// Devices of different segments are mixed, so their order is no part of an export and changes between exports,
+// but every line stays the same. Sorting the whole table would hold all of it in memory, so NDJSON exports, which
+// have one line for each device, are the ones to compare.
+func (self *AmazonWebServices) Export(encoder Encoder) error {
+	pages := make(chan []map[string]*dynamodb.AttributeValue, TotalSegments)
+	errs := make(chan error, TotalSegments)
+	// Closed when the export stops, so segments which are still being scanned stop as well.
+	done := make(chan struct{})
+	defer close(done)
+
+	var group sync.WaitGroup
+	for segment := int64(0); segment < TotalSegments; segment++ {
+		group.Add(1)
+		go func(segment int64) {
+			defer group.Done()
+			var startKey map[string]*dynamodb.AttributeValue
+			for {
+				result, err := self.ScanSegment(segment, startKey)
+				if err != nil {
+					errs <- err
+					return
+				}
+				select {
+				case pages <- result.Items:
+				case <-done:
+					return
+				}
+				// No LastEvaluatedKey means the last page of the segment has been reached.
+				if len(result.LastEvaluatedKey) == 0 {
+					return
+				}
+				startKey = result.LastEvaluatedKey
+			}
+		}(segment)
+	}
+	go func() {
+		group.Wait()
+		close(pages)
+	}()
+
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case items, ok := <-pages:
+			if !ok {
+				// A segment which has failed has sent its error before it was done.
+				select {
+				case err := <-errs:
+					return err
+				default:
+					return encoder.Flush()
+				}
+			}
+			for _, item := range items {
+				device := types.Device{}
+				// Deserialization/Decoding "item" to Go struct.
+				if err := dynamodbattribute.UnmarshalMap(item, &device); err != nil {
+					return err
+				}
+				if err := encoder.Encode(device); err != nil {
+					return err
+				}
+			}
+		}
+	}
+} // End of Export function
+
+// Upload streams the export to the exports bucket while it's being encoded, in parts of the multipart upload.
+func (self *AmazonWebServices) Upload(key string, format string) error {
+	// Get desire bucket's name from OS's environmental varible.
+	bucket := aws.String(os.Getenv("EXPORTS_BUCKET_NAME"))
+
+	reader, writer := io.Pipe()
+	go func() {
+		encoder, err := NewEncoder(format, writer)
+		if err == nil {
+			err = self.Export(encoder)
+		}
+		// A failed export fails the upload as well, then no partial file is left behind.
+		writer.CloseWithError(err)
+	}()
+
+	_, err := self.Uploader.Upload(&s3manager.UploadInput{
+		Bucket:             bucket,
+		Key:                aws.String(key),
+		Body:               reader,
+		ContentType:        aws.String(MediaTypes[format]),
+		ContentDisposition: aws.String("attachment; filename=\"" + key[strings.LastIndex(key, "/")+1:] + "\""),
+	})
+	// A failed upload stops the export, which would wait for the upload to read the rest otherwise.
+	reader.CloseWithError(err)
+	return err
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// Only a pending export is started, so a batch of the stream which is retried does not run an export twice.
+func (self *AmazonWebServices) Start(id string) (*dynamodb.UpdateItemOutput, error) {
+	return self.SetStatus(id, types.ExportPending, types.ExportRunning, "startedAt")
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// A running export is finished with the status it has ended with.
+func (self *AmazonWebServices) Finish(id string, status string) (*dynamodb.UpdateItemOutput, error) {
+	return self.SetStatus(id, types.ExportRunning, status, "finishedAt")
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// The export goes from one status to the next one, and the time it has done so is written in the given field.
+func (self *AmazonWebServices) SetStatus(id string, from string, to string, at string) (*dynamodb.UpdateItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("EXPORTS_TABLE_NAME"))
+
+	update := expression.Set(expression.Name("status"), expression.Value(to)).
+		Set(expression.Name(at), expression.Value(clock.Timestamp()))
+	condition := expression.Name("status").Equal(expression.Value(from))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	// Calling either UpdateItem function of interface, defined in runExport_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.UpdateItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function, with the changes of the exports table.
+// Each new export is run here, without the 29 seconds API Gateway waits for, and its status tells clients how it has ended.
+// A failed export only fails its own status. Errors of writing the status fail the whole batch, so Lambda retries it.
+func RunExport(event events.DynamoDBEvent) error {
+	for _, record := range event.Records {
+		// Only new exports are run, the stream also has the changes of their status.
+		if record.EventName != string(events.DynamoDBOperationTypeInsert) {
+			continue
+		}
+		image := record.Change.NewImage
+		id, key, format := image["id"].String(), image["key"].String(), image["format"].String()
+
+		_, err := TestAws.Start(id)
+		// The export has been started by an earlier attempt of the batch, and is left to it.
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			continue
+		}
+		if err != nil {
+			// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+			fmt.Println(fmt.Sprintf("Failed to start export %s: %s", id, err.Error()))
+			return err
+		}
+
+		status := types.ExportSucceeded
+		if err := TestAws.Upload(key, format); err != nil {
+			// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+			fmt.Println(fmt.Sprintf("Failed to export devices to %s: %s", key, err.Error()))
+			status = types.ExportFailed
+		}
+
+		if _, err := TestAws.Finish(id, status); err != nil {
+			// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+			fmt.Println(fmt.Sprintf("Failed to finish export %s: %s", id, err.Error()))
+			return err
+		}
+	}
+	return nil
+} // End of RunExport function
+
+func main() {
+	lambda.Start(RunExport)
+}
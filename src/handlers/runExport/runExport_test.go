@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"types"
+)
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Segment whose scan fails, -1 for none.
+	FailingSegment int64
+	// Segments the mock has been asked to scan.
+	lock     sync.Mutex
+	Segments map[int64]int
+	// Statuses the mocked UpdateItem function has written, by the ids of the exports.
+	Statuses map[string][]string
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of runExport.go for using in test scenarios.
+// "started_test" has been started by an earlier attempt already, and "throttled_test" can not be written at all.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	id := *input.Key["id"].S
+	switch id {
+	case "started_test":
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	case "throttled_test":
+		return nil, errors.New("Throttled")
+	}
+	// Values are the status the export comes from, the status it goes to and the time, the new status is the one
+	// which is not the condition: starting comes from pending, finishing from running.
+	from := types.ExportPending
+	for _, name := range input.ExpressionAttributeNames {
+		if *name == "finishedAt" {
+			from = types.ExportRunning
+		}
+	}
+	for _, value := range input.ExpressionAttributeValues {
+		switch *value.S {
+		case types.ExportRunning, types.ExportSucceeded, types.ExportFailed:
+			if *value.S != from {
+				self.Statuses[id] = append(self.Statuses[id], *value.S)
+			}
+		}
+	}
+	return new(dynamodb.UpdateItemOutput), nil
+}
+
+// Custom Scan function for overriding the Scan of runExport.go for using in test scenarios.
+// Each segment has two pages with a device on each, "<segment>-0" and "<segment>-1".
+func (self *MockDynamoDB) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	segment := *input.Segment
+	self.lock.Lock()
+	self.Segments[segment]++
+	self.lock.Unlock()
+
+	if segment == self.FailingSegment || *input.TotalSegments != TotalSegments || !*input.ConsistentRead {
+		return nil, errors.New("scan failed")
+	}
+
+	page := 0
+	if input.ExclusiveStartKey != nil {
+		page = 1
+	}
+	device := types.Device{
+		ID:          strconv.FormatInt(segment, 10) + "-" + strconv.Itoa(page),
+		DeviceModel: "/devicemodels/id1",
+		Name:        "Sensor",
+		Note:        "Testing, a \"sensor\" <1>",
+		Serial:      "A1",
+		Version:     2,
+	}
+	if segment == 0 && page == 1 {
+		device.DeletedAt = "2020-01-01T00:00:00Z"
+		device.Note = "Two\nlines"
+	}
+	if segment == 1 {
+		device.Tags = map[string]string{"site": "berlin", "env": "lab"}
+		device.Attributes = map[string]interface{}{"range": 100, "channel": "beta"}
+	}
+	item, _ := dynamodbattribute.MarshalMap(device)
+
+	output := &dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{item}}
+	if page == 0 {
+		output.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{"id": {S: aws.String(device.ID)}}
+	}
+	return output, nil
+}
+
+// Mocking the uploader through s3manageriface, it reads the whole body like S3 would.
+type MockUploader struct {
+	s3manageriface.UploaderAPI
+	Input *s3manager.UploadInput
+	Body  string
+}
+
+func (self *MockUploader) Upload(input *s3manager.UploadInput, options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	self.Input = input
+	body, err := ioutil.ReadAll(input.Body)
+	self.Body = string(body)
+	return &s3manager.UploadOutput{}, err
+}
+
+// Lines of the export, sorted, as devices of different segments are mixed.
+func SortedLines(text string, skip int) []string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	sorted := append([]string{}, lines[skip:]...)
+	sort.Strings(sorted)
+	return append(lines[:skip], sorted...)
+}
+
+// Export function in runExport.go signature: input: (encoder Encoder), output: (error)
+func TestExport(t *testing.T) {
+	mock := &MockDynamoDB{FailingSegment: -1, Segments: map[int64]int{}}
+	test_aws := &AmazonWebServices{DynamoDB: mock}
+
+	buffer := &bytes.Buffer{}
+	encoder, _ := NewEncoder(NDJSONFormat, buffer)
+	err := test_aws.Export(encoder)
+	lines := SortedLines(buffer.String(), 0)
+	if err != nil || len(lines) != 2*TotalSegments || len(mock.Segments) != TotalSegments {
+		t.Errorf("** Exporting every page of every segment ** \n \t<resulted segments: %v> <resulted export: \n%s>", mock.Segments, buffer.String())
+	}
+	expected := "{\"id\":\"0-1\",\"deviceModel\":\"/devicemodels/id1\",\"name\":\"Sensor\",\"note\":\"Two\\nlines\",\"serial\":\"A1\",\"deletedAt\":\"2020-01-01T00:00:00Z\",\"version\":2}"
+	if len(lines) != 0 && lines[1] != expected {
+		t.Errorf("** Exporting a deleted device as NDJSON ** \n \t<expected line: %s> \n \t<resulted line: %s>", expected, lines[1])
+	}
+
+	// A failing segment fails the whole export.
+	mock = &MockDynamoDB{FailingSegment: 2, Segments: map[int64]int{}}
+	test_aws = &AmazonWebServices{DynamoDB: mock}
+	encoder, _ = NewEncoder(CSVFormat, &bytes.Buffer{})
+	if err := test_aws.Export(encoder); err == nil {
+		t.Errorf("** Exporting with a failing segment ** \n \t<expected error> <resulted error: nil>")
+	}
+} // End of TestExport function
+
+// Record of the stream of the exports table, for a new export of the format.
+func Record(id string, format string) events.DynamoDBEventRecord {
+	record := events.DynamoDBEventRecord{EventName: "INSERT"}
+	record.Change.NewImage = map[string]events.DynamoDBAttributeValue{
+		"id":     events.NewStringAttribute(id),
+		"format": events.NewStringAttribute(format),
+		"key":    events.NewStringAttribute("exports/" + id + "." + format),
+		"status": events.NewStringAttribute(types.ExportPending),
+	}
+	return record
+}
+
+// RunExport function in runExport.go signature: input: (event events.DynamoDBEvent), output: (error)
+func TestRunExport(t *testing.T) {
+	uploader := &MockUploader{}
+	mock := &MockDynamoDB{FailingSegment: -1, Segments: map[int64]int{}, Statuses: map[string][]string{}}
+	TestAws = &AmazonWebServices{DynamoDB: mock, Uploader: uploader}
+
+	// Changes of the status of an export come through the stream as well, they do not run it again.
+	finished := Record("csv_test", CSVFormat)
+	finished.EventName = "MODIFY"
+	err := RunExport(events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{Record("started_test", CSVFormat), Record("csv_test", CSVFormat), finished}})
+	if err != nil || *uploader.Input.Key != "exports/csv_test.csv" || *uploader.Input.ContentType != "text/csv" || strings.Join(mock.Statuses["csv_test"], ",") != "running,succeeded" || len(mock.Statuses["started_test"]) != 0 {
+		t.Errorf("** Testing: CSV export. ** \n \t<resulted error: %v> <resulted statuses: %v>", err, mock.Statuses)
+	}
+
+	// Columns are always in the same order, and values are quoted only when they need it.
+	lines := SortedLines(uploader.Body, 1)
+	expected := []string{
+		"id,deviceModel,name,note,serial,tags,attributes,status,statusReason,createdAt,createdBy,updatedAt,updatedBy,version,deletedAt",
+		"0-0,/devicemodels/id1,Sensor,\"Testing, a \"\"sensor\"\" <1>\",A1,,,,,,,,,2,",
+		"0-1,/devicemodels/id1,Sensor,\"Two",
+		"1-0,/devicemodels/id1,Sensor,\"Testing, a \"\"sensor\"\" <1>\",A1,\"env=lab,site=berlin\",\"{\"\"channel\"\":\"\"beta\"\",\"\"range\"\":100}\",,,,,,,2,",
+	}
+	for index, line := range expected {
+		if index >= len(lines) || lines[index] != line {
+			t.Errorf("** Testing: CSV export. ** \n \t<expected export: \n%s> \n \t<resulted export: \n%s>", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+			break
+		}
+	}
+
+	// A failed export only fails its own status.
+	mock.FailingSegment = 0
+	err = RunExport(events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{Record("failing_test", NDJSONFormat)}})
+	if err != nil || strings.Join(mock.Statuses["failing_test"], ",") != "running,failed" {
+		t.Errorf("** Testing: Failing export. ** \n \t<resulted error: %v> <resulted statuses: %v>", err, mock.Statuses)
+	}
+
+	// A status which can not be written fails the batch, so Lambda retries it.
+	if err := RunExport(events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{Record("throttled_test", CSVFormat)}}); err == nil {
+		t.Errorf("** Testing: Failing to start an export. ** \n \t<expected error> <resulted error: nil>")
+	}
+} // End of TestRunExport function
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"os"
+	"search"
+	"sort"
+	"strconv"
+	"time"
+	"types"
+)
+
+// Number of devices returned when client does not ask for a specific number.
+const DefaultLimit = 25
+
+// Server enforced maximum number of results, bigger limits will be lowered to it.
+const MaxLimit = 100
+
+// Most words of a search text which are looked up, each one costs a query on the index.
+const MaxTerms = 10
+
+// Most index entries read for one term. A very common term, i.e: "sen", only ranks the devices it has read,
+// which are its heaviest entries, and the results are reported as partial.
+const MaxMatchesPerTerm = 1000
+
+// Global secondary index of the search index table on the term, sorted by weight.
+const TermWeightIndex = "term-weight-index"
+
+// Number of times unprocessed keys are sent again, before the request fails.
+const MaxAttempts = 5
+
+// Waiting time before the first retry, doubled on every next one.
+const BaseBackoff = 50 * time.Millisecond
+
+// Replaced in tests, so retries do not slow them down.
+var Sleep = time.Sleep
+
+// Struct containing what a search request asks for, after validation.
+type SearchQuery struct {
+	Terms []string
+	Limit int64
+}
+
+// Struct containing how well a device matches a search.
+type Match struct {
+	ID string
+	// Number of terms of the search found in the device.
+	Terms int
+	// Sum of the weights of the found terms, see search.DeviceTerms.
+	Score int64
+}
+
+// Struct containing the found devices, for marshalling/unmarshalling.
+// Partial results have left out entries of a very common term, so better matches may be missing.
+type SearchResult struct {
+	Devices []types.Device `json:"devices"`
+	Partial bool           `json:"partial,omitempty"`
+}
+
+// Struct containing an entry of the search index, for marshalling/unmarshalling.
+type Entry struct {
+	Term   string `json:"term"`
+	ID     string `json:"id"`
+	Weight int64  `json:"weight"`
+}
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's Query function inside, on the weight index of the search index table,
+// for the entries of a term, heaviest first. It also tells whether entries have been left out after MaxMatchesPerTerm.
+func (self *AmazonWebServices) QueryTerm(term string) ([]Entry, bool, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("SEARCH_INDEX_TABLE_NAME"))
+
+	keyCondition := expression.Key("term").Equal(expression.Value(term))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var input = &dynamodb.QueryInput{
+		TableName:                 tableName,
+		IndexName:                 aws.String(TermWeightIndex),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int64(MaxMatchesPerTerm),
+	}
+
+	entries := []Entry{}
+	for len(entries) < MaxMatchesPerTerm {
+		// Calling either Query function of interface, defined in searchDevices_test.go file, or api with the input we've provided.
+		result, err := self.DynamoDB.Query(input)
+		if err != nil {
+			return nil, false, err
+		}
+
+		page := []Entry{}
+		// Deserialization/Decoding "result.Items" to Go structs.
+		if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, false, err
+		}
+		entries = append(entries, page...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return entries, false, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+		input.Limit = aws.Int64(int64(MaxMatchesPerTerm - len(entries)))
+	}
+
+	// A LastEvaluatedKey after the last entry which has been read may still have nothing after it, the results are
+	// only reported as partial then, which is the safe side.
+	return entries, true, nil
+}
+
+// Preparing DynamoDB Session and Calling DB's BatchGetItem function inside, for the devices of the best matches.
+// Unprocessed keys are sent again with exponential backoff. Items are returned in no particular order.
+func (self *AmazonWebServices) BatchGet(ids []string) ([]map[string]*dynamodb.AttributeValue, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := os.Getenv("DEVICES_TABLE_NAME")
+	items := []map[string]*dynamodb.AttributeValue{}
+	if len(ids) == 0 {
+		return items, nil
+	}
+
+	// There are never more ids than MaxLimit, which DynamoDB accepts in one request.
+	keys := []map[string]*dynamodb.AttributeValue{}
+	for _, id := range ids {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}})
+	}
+	requestItems := map[string]*dynamodb.KeysAndAttributes{tableName: {Keys: keys}}
+
+	for attempt := 1; len(requestItems) != 0; attempt++ {
+		if attempt > MaxAttempts {
+			return nil, errors.New("Keys are still unprocessed after retrying.")
+		}
+		if attempt > 1 {
+			Sleep(BaseBackoff << uint(attempt-2))
+		}
+
+		// Calling either BatchGetItem function of interface, defined in searchDevices_test.go file, or api with the input we've provided.
+		result, err := self.DynamoDB.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: requestItems})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Responses[tableName]...)
+		requestItems = result.UnprocessedKeys
+	}
+
+	return items, nil
+}
+
+// The handler function which will be first started from main function.
+func SearchDevices(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// First & foremost we have to validate user input.
+	query, err := ValidateInputs(request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	entries := [][]Entry{}
+	partial := false
+	for _, term := range query.Terms {
+		found, truncated, err := TestAws.QueryTerm(term)
+		// If internal database errors occurred, return HTTP error code 500.
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+		entries = append(entries, found)
+		partial = partial || truncated
+	}
+
+	matches := Rank(entries, query.Limit)
+	ids := []string{}
+	for _, match := range matches {
+		ids = append(ids, match.ID)
+	}
+	items, err := TestAws.BatchGet(ids)
+
+	// Checking the result of the DynamoDB batch read.
+	return ValidateDatabaseResult(matches, items, partial, err), nil
+} // End of SearchDevices function
+
+func ValidateInputs(request events.APIGatewayProxyRequest) (SearchQuery, error) {
+	query := SearchQuery{Limit: DefaultLimit}
+
+	text := request.QueryStringParameters["q"]
+	if text == "" {
+		return SearchQuery{}, errors.New("Missing field: q")
+	}
+
+	query.Terms = search.QueryTerms(text)
+	if len(query.Terms) == 0 {
+		return SearchQuery{}, fmt.Errorf("Wrong format: q must have a word of at least %d letters or digits.", search.MinWordLength)
+	}
+	if len(query.Terms) > MaxTerms {
+		return SearchQuery{}, fmt.Errorf("Wrong format: q can have at most %d different words.", MaxTerms)
+	}
+
+	if value, ok := request.QueryStringParameters["limit"]; ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			return SearchQuery{}, errors.New("Wrong format: limit must be a positive number.")
+		}
+		query.Limit = parsed
+	}
+
+	// Bigger limits than the maximum are not an error, they will be lowered.
+	if query.Limit > MaxLimit {
+		query.Limit = MaxLimit
+	}
+
+	// Everything looks fine, return the terms to look up and how many devices to return.
+	return query, nil
+} // End of ValidateInputs function.
+
+// Rank returns the best matches of the entries found for each term of the search.
+// Devices having more terms of the search come first, then the ones with higher score. Ties are ordered by id.
+func Rank(entries [][]Entry, limit int64) []Match {
+	found := map[string]*Match{}
+	for _, termEntries := range entries {
+		for _, entry := range termEntries {
+			match, ok := found[entry.ID]
+			if !ok {
+				match = &Match{ID: entry.ID}
+				found[entry.ID] = match
+			}
+			match.Terms++
+			match.Score += entry.Weight
+		}
+	}
+
+	matches := []Match{}
+	for _, match := range found {
+		matches = append(matches, *match)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Terms != matches[j].Terms {
+			return matches[i].Terms > matches[j].Terms
+		}
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	if int64(len(matches)) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+} // End of Rank function
+
+// Devices are returned in the order of their rank. The index is updated shortly after each write,
+// so devices which have been deleted or purged in the meantime are skipped.
+func ValidateDatabaseResult(matches []Match, items []map[string]*dynamodb.AttributeValue, partial bool, err error) events.APIGatewayProxyResponse {
+	// If an internal error have occurred in the database, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error.",
+			StatusCode: 500,
+		}
+	}
+
+	found := map[string]types.Device{}
+	for _, item := range items {
+		device := types.Device{}
+		// Deserialization/Decoding "item" to Go struct.
+		dynamodbattribute.UnmarshalMap(item, &device)
+		if device.DeletedAt == "" {
+			found[device.ID] = device
+		}
+	}
+
+	page := SearchResult{Devices: []types.Device{}, Partial: partial}
+	for _, match := range matches {
+		if device, ok := found[match.ID]; ok {
+			page.Devices = append(page.Devices, device)
+		}
+	}
+
+	// Serialization/Encoding page to JSON.
+	jsonResponse, _ := json.Marshal(page)
+
+	// Return founded devices as JSON type with 200 HTTP status code, even if none was found.
+	return events.APIGatewayProxyResponse{
+		Body:       string(jsonResponse),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		StatusCode: 200,
+	}
+} // End of ValidateDatabaseResult function
+
+func main() {
+	lambda.Start(SearchDevices)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"search"
+	"strconv"
+	"testing"
+	"time"
+	"types"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+// Devices on the mocked DB. "3" has been deleted after it was indexed.
+var MockDevices = []types.Device{
+	{ID: "1", DeviceModel: "/devicemodels/id1", Name: "Sensor", Note: "Testing a sensor.", Serial: "A1"},
+	{ID: "2", DeviceModel: "/devicemodels/id1", Name: "Gauge", Note: "Sensor for tests.", Serial: "A2"},
+	{ID: "3", DeviceModel: "/devicemodels/id1", Name: "Sensor", Note: "Old.", Serial: "A3", DeletedAt: "2020-01-01T00:00:00Z"},
+}
+
+// Custom Query function for overriding the Query of searchDevices.go for using in test scenarios.
+// Entries are indexed from MockDevices, and returned one per page. "common" is a term of more devices than are read.
+func (self *MockDynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	// Entries have to be read heaviest first, so the best matches of a common term are among the ones read.
+	if input.IndexName == nil || *input.IndexName != TermWeightIndex || input.ScanIndexForward == nil || *input.ScanIndexForward {
+		return nil, errors.New("Entries are not read by weight")
+	}
+	term := *input.ExpressionAttributeValues[":0"].S
+	output := new(dynamodb.QueryOutput)
+	if term == "common" {
+		for i := int64(0); i < *input.Limit; i++ {
+			item, _ := dynamodbattribute.MarshalMap(Entry{Term: term, ID: "common_" + strconv.FormatInt(i, 10), Weight: 1})
+			output.Items = append(output.Items, item)
+		}
+		output.SetLastEvaluatedKey(map[string]*dynamodb.AttributeValue{"term": {S: aws.String(term)}, "id": {S: aws.String("common")}})
+		return output, nil
+	}
+
+	start := 0
+	if input.ExclusiveStartKey != nil {
+		start, _ = strconv.Atoi(*input.ExclusiveStartKey["id"].S)
+	}
+
+	for _, device := range MockDevices {
+		id, _ := strconv.Atoi(device.ID)
+		undeleted := device
+		undeleted.DeletedAt = ""
+		if weight, ok := search.DeviceTerms(undeleted)[term]; ok && id > start {
+			item, _ := dynamodbattribute.MarshalMap(Entry{Term: term, ID: device.ID, Weight: weight})
+			output.SetItems([]map[string]*dynamodb.AttributeValue{item})
+			output.SetLastEvaluatedKey(map[string]*dynamodb.AttributeValue{"term": {S: aws.String(term)}, "id": {S: aws.String(device.ID)}})
+			break
+		}
+	}
+	return output, nil
+}
+
+// Custom BatchGetItem function for overriding the BatchGetItem of searchDevices.go for using in test scenarios.
+func (self *MockDynamoDB) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	output := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]*dynamodb.AttributeValue{}}
+	for table, keys := range input.RequestItems {
+		for _, key := range keys.Keys {
+			for _, device := range MockDevices {
+				if device.ID == *key["id"].S {
+					item, _ := dynamodbattribute.MarshalMap(device)
+					output.Responses[table] = append(output.Responses[table], item)
+				}
+			}
+		}
+	}
+	return output, nil
+}
+
+// Rank function in searchDevices.go signature: input: (entries [][]Entry, limit int64), output: ([]Match)
+func TestRank(t *testing.T) {
+	entries := [][]Entry{
+		{{ID: "a", Weight: 6}, {ID: "b", Weight: 1}, {ID: "c", Weight: 1}},
+		{{ID: "b", Weight: 1}, {ID: "c", Weight: 2}},
+	}
+
+	// "a" has the highest score, but only one of the terms.
+	matches := Rank(entries, 2)
+	if len(matches) != 2 || matches[0].ID != "c" || matches[1].ID != "b" {
+		t.Errorf("** Ranking by terms, then by score ** \n \t<expected ids: c, b> <resulted matches: %v>", matches)
+	}
+} // End of TestRank function
+
+// SearchDevices function in searchDevices.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestSearchDevices(t *testing.T) {
+	Sleep = func(time.Duration) {}
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	sensor := "{\"id\":\"1\",\"deviceModel\":\"/devicemodels/id1\",\"name\":\"Sensor\",\"note\":\"Testing a sensor.\",\"serial\":\"A1\"}"
+	gauge := "{\"id\":\"2\",\"deviceModel\":\"/devicemodels/id1\",\"name\":\"Gauge\",\"note\":\"Sensor for tests.\",\"serial\":\"A2\"}"
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Missing search text. **",
+			Request:            events.APIGatewayProxyRequest{},
+			ExpectedBody:       "Missing field: q",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: No searchable word. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"q": "a !"}},
+			ExpectedBody:       "Wrong format: q must have a word of at least 2 letters or digits.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Wrong limit. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"q": "sensor", "limit": "0"}},
+			ExpectedBody:       "Wrong format: limit must be a positive number.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Stemmed words of the note. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"q": "Testing a sensor"}},
+			ExpectedBody:       "{\"devices\":[" + sensor + "," + gauge + "]}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Prefix of the name. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"q": "GAU"}},
+			ExpectedBody:       "{\"devices\":[" + gauge + "]}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Limited results. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"q": "sensors", "limit": "1"}},
+			ExpectedBody:       "{\"devices\":[" + sensor + "]}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Term of too many devices. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"q": "common"}},
+			ExpectedBody:       "{\"devices\":[],\"partial\":true}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Nothing found. **",
+			Request:            events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"q": "thermometer"}},
+			ExpectedBody:       "{\"devices\":[]}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := SearchDevices(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestSearchDevices function
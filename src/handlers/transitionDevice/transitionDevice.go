@@ -0,0 +1,253 @@
+package main
+
+import (
+	"actor"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"lifecycle"
+	"os"
+	"strings"
+	"types"
+	"unicode/utf8"
+	"versioning"
+)
+
+// Number of times a transition is tried again, when the device is changed by others while it's being moved.
+const MaxAttempts = 3
+
+// Longest reason which can be given for a transition.
+const MaxReasonLength = 500
+
+// Struct of the body which client sends to move a device to another state.
+type Transition struct {
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+}
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside.
+func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	// Calling either GetItem function of interface, defined in transitionDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's UpdateItem function inside.
+// The item is only updated if it still has the status which the transition has been checked against,
+// and the version which it has been read with.
+func (self *AmazonWebServices) Update(id string, from string, transition Transition, by string, version int64) (*dynamodb.UpdateItemOutput, error) {
+	// Get desire table's name from OS's environmental varible.
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	update := expression.Set(expression.Name(lifecycle.Attribute), expression.Value(transition.To)).
+		Set(expression.Name("statusReason"), expression.Value(transition.Reason))
+	// Transitions must never create a new item, nor change a deleted one.
+	condition := expression.AttributeExists(expression.Name("id")).
+		And(expression.AttributeNotExists(expression.Name("deletedAt"))).
+		And(lifecycle.Condition(from)).
+		And(versioning.Condition(version))
+
+	expr, err := expression.NewBuilder().WithUpdate(versioning.Increment(actor.Stamp(update, by))).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	}
+
+	// Calling either UpdateItem function of interface, defined in transitionDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.UpdateItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func TransitionDevice(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which user has sent through POST method.
+	id := request.PathParameters["id"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : id",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// First & foremost we have to validate user input.
+	transition, err := ValidateInputs(request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// Transitions can be made safe by sending the ETag of the device in If-Match header.
+	expectedVersion, checkVersion, err := versioning.IfMatch(request.Headers)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		// The transition is checked against the status the device has right now.
+		result, err := TestAws.Get(id)
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+
+		CurrentDevice := types.Device{}
+		dynamodbattribute.UnmarshalMap(result.Item, &CurrentDevice)
+
+		// Deleted devices can only be restored, not moved.
+		if len(result.Item) == 0 || CurrentDevice.DeletedAt != "" {
+			return events.APIGatewayProxyResponse{
+				Body:       "Desired device not found.",
+				StatusCode: 404,
+			}, nil
+		}
+
+		// The device has another version than the client has expected, return HTTP error code 412.
+		if checkVersion && CurrentDevice.Version != expectedVersion {
+			return versioning.PreconditionFailed(CurrentDevice.Version), nil
+		}
+
+		// The current state does not lead to the asked one, return HTTP error code 409 with the states it leads to.
+		if !lifecycle.CanTransition(CurrentDevice.Status, transition.To) {
+			return lifecycle.IllegalTransition(CurrentDevice.Status, transition.To), nil
+		}
+
+		updated, err := TestAws.Update(id, CurrentDevice.Status, transition, actor.From(request), CurrentDevice.Version)
+
+		// The device has been moved, changed or deleted since it was read. Read it again and find out what happened.
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			if attempt < MaxAttempts {
+				continue
+			}
+			// Too many writers on the same device, return HTTP error code 409.
+			return events.APIGatewayProxyResponse{
+				Body:       "Conflict: The device is being changed by others, please retry.",
+				StatusCode: 409,
+			}, nil
+		}
+
+		// If internal database errors occurred, return HTTP error code 500.
+		if err != nil {
+			return events.APIGatewayProxyResponse{
+				Body:       "Internal Server Error\nDatabase error.",
+				StatusCode: 500,
+			}, nil
+		}
+
+		// Return the whole device, as DynamoDB has stored it.
+		UpdatedDevice := types.Device{}
+		dynamodbattribute.UnmarshalMap(updated.Attributes, &UpdatedDevice)
+
+		jsonResponse, _ := json.Marshal(UpdatedDevice)
+		return events.APIGatewayProxyResponse{
+			Body:    string(jsonResponse),
+			Headers: map[string]string{"ETag": versioning.ETag(UpdatedDevice.Version)},
+			// Everything looks fine, return HTTP 200
+			StatusCode: 200,
+		}, nil
+	}
+} // End of TransitionDevice function
+
+func ValidateInputs(request events.APIGatewayProxyRequest) (Transition, error) {
+	transition := Transition{}
+
+	if len(request.Body) == 0 {
+		return Transition{}, errors.New("No inputs provided, please provide inputs in JSON format.")
+	}
+
+	if err := json.Unmarshal([]byte(request.Body), &transition); err != nil {
+		return Transition{}, errors.New("Wrong format: Inputs must be a valid JSON.")
+	}
+
+	if transition.To == "" {
+		return Transition{}, errors.New("Missing field: to, i.e: {\"to\": \"active\", \"reason\": \"Installed on site.\"}")
+	}
+	if err := lifecycle.Validate(transition.To); err != nil {
+		return Transition{}, err
+	}
+
+	// Whoever reads the device later has to know why it has been moved.
+	transition.Reason = strings.TrimSpace(transition.Reason)
+	if transition.Reason == "" {
+		return Transition{}, errors.New("Missing field: reason, i.e: {\"to\": \"active\", \"reason\": \"Installed on site.\"}")
+	}
+	if utf8.RuneCountInString(transition.Reason) > MaxReasonLength {
+		return Transition{}, fmt.Errorf("Wrong format: reason can be at most %d characters.", MaxReasonLength)
+	}
+
+	// Everything looks fine, return the transition.
+	return transition, nil
+} // End of ValidateInputs function.
+
+func main() {
+	lambda.Start(TransitionDevice)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked UpdateItem function has received.
+	UpdateInput *dynamodb.UpdateItemInput
+}
+
+// The stored device. "legacy_test" has been written before the lifecycle, so it has no status.
+func MockItem(id string) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{
+		"id":          &dynamodb.AttributeValue{S: aws.String(id)},
+		"deviceModel": &dynamodb.AttributeValue{S: aws.String("deviceModel_test")},
+		"name":        &dynamodb.AttributeValue{S: aws.String("name_test")},
+		"note":        &dynamodb.AttributeValue{S: aws.String("note_test")},
+		"serial":      &dynamodb.AttributeValue{S: aws.String("serial_test")},
+		"status":      &dynamodb.AttributeValue{S: aws.String("active")},
+		"version":     &dynamodb.AttributeValue{N: aws.String("1")},
+	}
+	switch id {
+	case "legacy_test":
+		delete(item, "status")
+	case "retired_test":
+		item["status"] = &dynamodb.AttributeValue{S: aws.String("retired")}
+	}
+	return item
+}
+
+// Custom GetItem function for overriding the GetItem of transitionDevice.go for using in test scenarios.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	mockOutput := new(dynamodb.GetItemOutput)
+	if id := *input.Key["id"].S; id != "missing_test" {
+		mockOutput.SetItem(MockItem(id))
+	}
+	return mockOutput, nil
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of transitionDevice.go for using in test scenarios.
+// Mocking UpdateItem output to the stored item with the status and the reason of the update.
+// "busy_test" is moved by someone else every time it is read, so its condition always fails.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	self.UpdateInput = input
+	if *input.Key["id"].S == "busy_test" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	item := MockItem(*input.Key["id"].S)
+	for placeholder, name := range input.ExpressionAttributeNames {
+		if *name != "status" && *name != "statusReason" {
+			continue
+		}
+		// The new value is the one which the update expression sets the name to.
+		for key, value := range input.ExpressionAttributeValues {
+			if strings.Contains(*input.UpdateExpression, placeholder+" = "+key) {
+				item[*name] = value
+			}
+		}
+	}
+	item["version"] = &dynamodb.AttributeValue{N: aws.String("2")}
+	return &dynamodb.UpdateItemOutput{Attributes: item}, nil
+}
+
+// Update function in transitionDevice.go signature: input: (id string, from string, transition Transition, by string, version int64), output: (*dynamodb.UpdateItemOutput, error)
+func TestUpdate(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	// The transition only happens while the device is still in the state it has been checked against.
+	_, err := test_aws.Update("id_test", "active", Transition{To: "maintenance", Reason: "Firmware update."}, "alice", 1)
+	input := mock.UpdateInput
+	if err != nil || !strings.Contains(*input.ConditionExpression, "attribute_not_exists") || strings.Contains(*input.UpdateExpression, "maintenance") || *input.ReturnValues != "ALL_NEW" {
+		t.Errorf("** Moving a device ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+
+	// Devices without a status are only moved while they still have none.
+	test_aws.Update("legacy_test", "", Transition{To: "active", Reason: "Installed on site."}, "alice", 1)
+	if input := mock.UpdateInput; strings.Count(*input.ConditionExpression, "attribute_not_exists") != 2 {
+		t.Errorf("** Moving a device without status ** \n \t<resulted input: \n%s>", input.GoString())
+	}
+} // End of TestUpdate function
+
+// TransitionDevice function in transitionDevice.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestTransitionDevice(t *testing.T) {
+	TestCases := []TestCase{
+		{
+			Name:               "** Testing: Missing id. **",
+			Request:            events.APIGatewayProxyRequest{Body: "{\"to\":\"maintenance\",\"reason\":\"Firmware update.\"}"},
+			ExpectedBody:       "Missing field : id",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Missing state. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"reason\":\"Firmware update.\"}"},
+			ExpectedBody:       "Missing field: to, i.e: {\"to\": \"active\", \"reason\": \"Installed on site.\"}",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Unknown state. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"to\":\"broken\",\"reason\":\"Firmware update.\"}"},
+			ExpectedBody:       "Unknown status: broken, a status can be provisioning, active, maintenance or retired.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Missing reason. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"to\":\"maintenance\",\"reason\":\"  \"}"},
+			ExpectedBody:       "Missing field: reason, i.e: {\"to\": \"active\", \"reason\": \"Installed on site.\"}",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Device not found. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "missing_test"}, Body: "{\"to\":\"maintenance\",\"reason\":\"Firmware update.\"}"},
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Stale If-Match. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"If-Match": "\"7\""}, Body: "{\"to\":\"maintenance\",\"reason\":\"Firmware update.\"}"},
+			ExpectedBody:       "{\"code\":\"PreconditionFailed\",\"message\":\"The device has been changed since it was read, read it again and retry.\",\"currentVersion\":1}",
+			ExpectedStatusCode: 412,
+		},
+
+		{
+			Name:               "** Testing: Illegal transition. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"to\":\"provisioning\",\"reason\":\"Set up again.\"}"},
+			ExpectedBody:       "{\"code\":\"IllegalTransition\",\"message\":\"A device can not move from active to provisioning. It can move to maintenance, retired.\",\"currentStatus\":\"active\",\"allowedStates\":[\"maintenance\",\"retired\"]}",
+			ExpectedStatusCode: 409,
+		},
+
+		{
+			Name:               "** Testing: Retired device. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "retired_test"}, Body: "{\"to\":\"active\",\"reason\":\"Back in service.\"}"},
+			ExpectedBody:       "{\"code\":\"IllegalTransition\",\"message\":\"A device can not move from retired to active.\",\"currentStatus\":\"retired\",\"allowedStates\":[]}",
+			ExpectedStatusCode: 409,
+		},
+
+		{
+			Name:               "** Testing: Moving a device. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"If-Match": "\"1\""}, Body: "{\"to\":\"maintenance\",\"reason\":\"Firmware update.\"}"},
+			ExpectedBody:       "{\"id\":\"id_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\",\"status\":\"maintenance\",\"statusReason\":\"Firmware update.\",\"version\":2}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Device without status counts as provisioning. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "legacy_test"}, Body: "{\"to\":\"active\",\"reason\":\"Installed on site.\"}"},
+			ExpectedBody:       "{\"id\":\"legacy_test\",\"deviceModel\":\"deviceModel_test\",\"name\":\"name_test\",\"note\":\"note_test\",\"serial\":\"serial_test\",\"status\":\"active\",\"statusReason\":\"Installed on site.\",\"version\":2}",
+			ExpectedStatusCode: 200,
+		},
+
+		{
+			Name:               "** Testing: Device is being changed by others. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "busy_test"}, Body: "{\"to\":\"maintenance\",\"reason\":\"Firmware update.\"}"},
+			ExpectedBody:       "Conflict: The device is being changed by others, please retry.",
+			ExpectedStatusCode: 409,
+		},
+	}
+
+	for _, test := range TestCases {
+		// Executing each test cases scenario.
+		TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+		response, _ := TransitionDevice(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestTransitionDevice function
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"actor"
+	"devicemodels"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"os"
+	"types"
+	"versioning"
+)
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's TransactWriteItems function inside, through devicemodels.Update.
+// Unlike a plain put, the item is only replaced when it already exists on DB and has not been deleted.
+// If checkVersion is true, the item is only replaced when its stored version is expectedVersion.
+func (self *AmazonWebServices) Replace(device types.Device, expectedVersion int64, checkVersion bool) (*dynamodb.UpdateItemOutput, error) {
+	// Get table name from OS's environment
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	update := expression.Set(expression.Name("deviceModel"), expression.Value(device.DeviceModel)).
+		Set(expression.Name("name"), expression.Value(device.Name)).
+		Set(expression.Name("note"), expression.Value(device.Note)).
+		Set(expression.Name("serial"), expression.Value(device.Serial)).
+		Set(expression.Name("serialKey"), expression.Value(types.NormalizeSerial(device.Serial)))
+	// The device is replaced as a whole, so one sent without tags or attributes has none left.
+	if len(device.Tags) != 0 {
+		update = update.Set(expression.Name("tags"), expression.Value(device.Tags))
+	} else {
+		update = update.Remove(expression.Name("tags"))
+	}
+	if len(device.Attributes) != 0 {
+		update = update.Set(expression.Name("attributes"), expression.Value(device.Attributes))
+	} else {
+		update = update.Remove(expression.Name("attributes"))
+	}
+	update = actor.Stamp(update, device.UpdatedBy)
+	condition := expression.AttributeExists(expression.Name("id")).
+		And(expression.AttributeNotExists(expression.Name("deletedAt")))
+	if checkVersion {
+		condition = condition.And(versioning.Condition(expectedVersion))
+	}
+
+	expr, err := expression.NewBuilder().WithUpdate(versioning.Increment(update)).WithCondition(condition).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var input = &dynamodb.UpdateItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(device.ID),
+			},
+		},
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	}
+	// The device may get another device model, so the model is checked in the same transaction, see devicemodels.Update.
+	// Calling either TransactWriteItems function of interface, defined in updateDevice_test.go file, or api with the input we've provided.
+	result, err := devicemodels.Update(self.DynamoDB, input, device, true)
+	return result, err
+}
+
+// Preparing DynamoDB Session and Calling DB's GetItem function inside.
+func (self *AmazonWebServices) Get(id string) (*dynamodb.GetItemOutput, error) {
+	// Get table name from OS's environment
+	tableName := aws.String(os.Getenv("DEVICES_TABLE_NAME"))
+
+	var input = &dynamodb.GetItemInput{
+		TableName: tableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {
+				S: aws.String(id),
+			},
+		},
+	}
+
+	// Calling either GetItem function of interface, defined in updateDevice_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.GetItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func UpdateDevice(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which user has sent through PUT method.
+	id := request.PathParameters["id"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : id",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// First & foremost we have to validate user input.
+	UpdatedDevice, err := ValidateInputs(id, request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// Replacing can be made safe by sending the ETag of the device in If-Match header.
+	expectedVersion, checkVersion, err := versioning.IfMatch(request.Headers)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// The device model has to exist, so typos do not create phantom models.
+	if err = devicemodels.Check(TestAws.DynamoDB, UpdatedDevice); err != nil {
+		if devicemodels.IsClientError(err) {
+			return events.APIGatewayProxyResponse{
+				Body:       err.Error(),
+				StatusCode: 400,
+			}, nil
+		}
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Stamped with when and by whom it has been replaced, for auditing and the history of the device.
+	UpdatedDevice.UpdatedBy = actor.From(request)
+	result, err := TestAws.Replace(UpdatedDevice, expectedVersion, checkVersion)
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ConditionFailedResponse(id, checkVersion), nil
+	}
+
+	// The device model has been deleted since it was checked, or is being deleted. Return HTTP error code 400.
+	if devicemodels.IsClientError(err) {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Deserialization/Decoding "result.Attributes" to Go struct, it has the new version of the device.
+	ReplacedDevice := types.Device{}
+	dynamodbattribute.UnmarshalMap(result.Attributes, &ReplacedDevice)
+
+	// Serialization/Encoding "ReplacedDevice" to JSON.
+	jsonResponse, _ := json.Marshal(ReplacedDevice)
+	return events.APIGatewayProxyResponse{
+		Body:    string(jsonResponse),
+		Headers: map[string]string{"ETag": versioning.ETag(ReplacedDevice.Version)},
+		// Everything looks fine, return HTTP 200
+		StatusCode: 200,
+	}, nil
+} // End of UpdateDevice function
+
+// Tells apart why the condition of the write has failed, by reading the device as it is now.
+func ConditionFailedResponse(id string, checkVersion bool) events.APIGatewayProxyResponse {
+	NotFound := events.APIGatewayProxyResponse{
+		Body:       "Desired device not found.",
+		StatusCode: 404,
+	}
+
+	// Without a version check, the device can only be missing or deleted, return HTTP error code 404.
+	if !checkVersion {
+		return NotFound
+	}
+
+	result, err := TestAws.Get(id)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}
+	}
+
+	CurrentDevice := types.Device{}
+	dynamodbattribute.UnmarshalMap(result.Item, &CurrentDevice)
+
+	if len(result.Item) == 0 || CurrentDevice.DeletedAt != "" {
+		return NotFound
+	}
+
+	// The device exists but has another version than the client has expected, return HTTP error code 412.
+	return versioning.PreconditionFailed(CurrentDevice.Version)
+} // End of ConditionFailedResponse function
+
+func ValidateInputs(id string, request events.APIGatewayProxyRequest) (types.Device, error) {
+	// De-serialize "request.Body" which is in JSON format into "UpdatedDevice" in Go object.
+	UpdatedDevice, err := types.ParseDevice(request.Body)
+	if err != nil {
+		return types.Device{}, err
+	}
+
+	// The id may be left out of the body, the one in the path is used then.
+	if len(UpdatedDevice.ID) == 0 {
+		UpdatedDevice.ID = id
+	}
+
+	if UpdatedDevice.ID != id {
+		return types.Device{}, errors.New("Wrong id: The id in the body does not match the id in the path.")
+	}
+
+	// Same rules as adding a new device.
+	if err = UpdatedDevice.Validate(); err != nil {
+		return types.Device{}, err
+	}
+
+	// Everything looks fine, return UpdatedDevice in Go struct.
+	return UpdatedDevice, nil
+} // End of ValidateInputs function.
+
+func main() {
+	lambda.Start(UpdateDevice)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"clock"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"types"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked UpdateItem function has received.
+	Input *dynamodb.UpdateItemInput
+	// Last input the mocked TransactWriteItems function has received.
+	Transaction *dynamodb.TransactWriteItemsInput
+	// The device as the mocked TransactWriteItems function has written it.
+	Replaced map[string]*dynamodb.AttributeValue
+}
+
+// Custom TransactWriteItems function for overriding the TransactWriteItems of devicemodels.Update for using in test scenarios.
+// The device is written like the mocked UpdateItem does, "deletingModel" is being deleted on the mocked DB.
+func (self *MockDynamoDB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	self.Transaction = input
+	update := input.TransactItems[0].Update
+	output, err := self.UpdateItem(&dynamodb.UpdateItemInput{
+		Key:                       update.Key,
+		UpdateExpression:          update.UpdateExpression,
+		ConditionExpression:       update.ConditionExpression,
+		ExpressionAttributeNames:  update.ExpressionAttributeNames,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
+	})
+
+	reasons := []*dynamodb.CancellationReason{{Code: aws.String("None")}, {Code: aws.String("None")}, {Code: aws.String("None")}}
+	if err != nil {
+		reasons[0].Code = aws.String("ConditionalCheckFailed")
+		return nil, &dynamodb.TransactionCanceledException{CancellationReasons: reasons}
+	}
+	if *input.TransactItems[1].ConditionCheck.Key["id"].S == "deletingModel" {
+		reasons[1].Code = aws.String("ConditionalCheckFailed")
+		return nil, &dynamodb.TransactionCanceledException{CancellationReasons: reasons}
+	}
+	self.Replaced = output.Attributes
+	return new(dynamodb.TransactWriteItemsOutput), nil
+}
+
+// Custom UpdateItem function for overriding the UpdateItem of updateDevice.go for using in test scenarios.
+// Only "id_test" exists on the mocked DB with version 1, other ids and versions fail the condition like on a real DB.
+func (self *MockDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	self.Input = input
+	conditionFailed := awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	if *input.Key["id"].S != "id_test" {
+		return nil, conditionFailed
+	}
+	// Besides the expected version, the only number in the input is the increment of the version.
+	for _, value := range input.ExpressionAttributeValues {
+		if value.N != nil && *value.N != "1" {
+			return nil, conditionFailed
+		}
+	}
+
+	MockOutput := new(dynamodb.UpdateItemOutput)
+	MockOutput.SetAttributes(
+		map[string]*dynamodb.AttributeValue{
+			"id":          &dynamodb.AttributeValue{S: aws.String("id_test")},
+			"deviceModel": &dynamodb.AttributeValue{S: aws.String("/devicemodels/testDeviceModel")},
+			"name":        &dynamodb.AttributeValue{S: aws.String("testName")},
+			"note":        &dynamodb.AttributeValue{S: aws.String("testNote")},
+			"serial":      &dynamodb.AttributeValue{S: aws.String("testSerial")},
+			"version":     &dynamodb.AttributeValue{N: aws.String("2")},
+		},
+	)
+	return MockOutput, nil
+}
+
+// Custom GetItem function for overriding the GetItem of updateDevice.go for using in test scenarios.
+func (self *MockDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	MockOutput := new(dynamodb.GetItemOutput)
+	// Only "testDeviceModel" and "deletingModel" exist on the mocked device models table.
+	if *input.TableName == "device_models_test" {
+		if id := *input.Key["id"].S; id == "testDeviceModel" || id == "deletingModel" {
+			MockOutput.SetItem(map[string]*dynamodb.AttributeValue{"id": input.Key["id"]})
+		}
+		return MockOutput, nil
+	}
+	// A device is read consistently right after it has been written.
+	if input.ConsistentRead != nil && *input.ConsistentRead {
+		MockOutput.SetItem(self.Replaced)
+		return MockOutput, nil
+	}
+	if *input.Key["id"].S == "id_test" {
+		MockOutput.SetItem(
+			map[string]*dynamodb.AttributeValue{
+				"id":      &dynamodb.AttributeValue{S: aws.String("id_test")},
+				"version": &dynamodb.AttributeValue{N: aws.String("1")},
+			},
+		)
+	}
+	return MockOutput, nil
+}
+
+// Replace function in updateDevice.go signature: input: (device types.Device, expectedVersion int64, checkVersion bool), output: (*dynamodb.UpdateItemOutput, error)
+func TestReplace(t *testing.T) {
+	mock := &MockDynamoDB{}
+	test_aws := new(AmazonWebServices)
+	test_aws.DynamoDB = mock
+
+	_, err := test_aws.Replace(types.Device{ID: "not_existed", DeviceModel: "/devicemodels/testDeviceModel"}, 0, false)
+
+	// Replacing must never create a new item, nor bring back a deleted one.
+	condition := *mock.Input.ConditionExpression
+	if err == nil || !strings.Contains(condition, "attribute_exists") || !strings.Contains(condition, "attribute_not_exists") {
+		t.Errorf("** Replacing not existed item ** \n \t<resulted input: \n%s>", mock.Input.GoString())
+	}
+
+	_, err = test_aws.Replace(types.Device{ID: "id_test", DeviceModel: "/devicemodels/testDeviceModel"}, 1, true)
+
+	// The device model is checked and referenced in the same transaction, and the new version is read back.
+	if err != nil || len(mock.Transaction.TransactItems) != 3 || mock.Transaction.TransactItems[1].ConditionCheck == nil || mock.Replaced == nil {
+		t.Errorf("** Replacing item with current version ** \n \t<resulted input: \n%s>", mock.Transaction.GoString())
+	}
+
+	// The device is replaced as a whole, one sent without tags or attributes has none of them left.
+	removed := []string{}
+	for _, clause := range strings.Split(*mock.Input.UpdateExpression, "\n") {
+		if strings.HasPrefix(clause, "REMOVE ") {
+			for _, placeholder := range strings.Split(strings.TrimPrefix(clause, "REMOVE "), ", ") {
+				removed = append(removed, *mock.Input.ExpressionAttributeNames[placeholder])
+			}
+		}
+	}
+	sort.Strings(removed)
+	if strings.Join(removed, ",") != "attributes,tags" {
+		t.Errorf("** Replacing item without tags and attributes ** \n \t<resulted removed: %v> <resulted input: \n%s>", removed, mock.Input.GoString())
+	}
+
+	// The replacement is the last write of the device, its creation is left as it is.
+	clock.Now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	test_aws.Replace(types.Device{ID: "id_test", DeviceModel: "/devicemodels/testDeviceModel", UpdatedBy: "alice"}, 1, true)
+	names := []string{}
+	for _, name := range mock.Input.ExpressionAttributeNames {
+		names = append(names, *name)
+	}
+	stamped := strings.Join(names, ",")
+	if !strings.Contains(mock.Input.GoString(), "\"2020-01-02T03:04:05Z\"") || !strings.Contains(mock.Input.GoString(), "\"alice\"") || !strings.Contains(stamped, "updatedAt") || strings.Contains(stamped, "createdAt") {
+		t.Errorf("** Replacing item stamps its last write ** \n \t<resulted input: \n%s>", mock.Input.GoString())
+	}
+} // End of TestReplace function.
+
+// UpdateDevice function in updateDevice.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestUpdateDevice(t *testing.T) {
+	os.Setenv("DEVICE_MODELS_TABLE_NAME", "device_models_test")
+	TestAws = &AmazonWebServices{DynamoDB: &MockDynamoDB{}}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Empty id input. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": ""}},
+			ExpectedBody:       "Missing field : id",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Empty body input. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: ""},
+			ExpectedBody:       "No inputs provided, please provide inputs in JSON format.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: JSON with missing field - Serial **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"id\":\"id_test\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"\"}"},
+			ExpectedBody:       "Missing field: Serial",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Body id disagrees with path id. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"id\":\"other_id\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "Wrong id: The id in the body does not match the id in the path.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Unknown device model. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"deviceModel\":\"/devicemodels/typo\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "Unknown device model: /devicemodels/typo",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Device model which is being deleted. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"deviceModel\":\"/devicemodels/deletingModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "Unknown device model: /devicemodels/deletingModel",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Device model is not a reference. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"deviceModel\":\"testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "Wrong format: deviceModel must be a reference like /devicemodels/id1.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Device does not exist. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "not_existed"}, Body: "{\"id\":\"not_existed\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Outdated If-Match. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Headers: map[string]string{"If-Match": "\"5\""}, Body: "{\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "{\"code\":\"PreconditionFailed\",\"message\":\"The device has been changed since it was read, read it again and retry.\",\"currentVersion\":1}",
+			ExpectedStatusCode: 412,
+		},
+
+		{
+			Name:               "** Testing: If-Match of a device which does not exist. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "not_existed"}, Headers: map[string]string{"If-Match": "\"1\""}, Body: "{\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "Desired device not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Id taken from path. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"id": "id_test"}, Body: "{\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\"}"},
+			ExpectedBody:       "{\"id\":\"id_test\",\"deviceModel\":\"/devicemodels/testDeviceModel\",\"name\":\"testName\",\"note\":\"testNote\",\"serial\":\"testSerial\",\"version\":2}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := UpdateDevice(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+} // End of TestUpdateDevice function
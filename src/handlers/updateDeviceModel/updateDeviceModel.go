@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"types"
+)
+
+type AmazonWebServices struct {
+	Config   *aws.Config
+	Session  *session.Session
+	DynamoDB dynamodbiface.DynamoDBAPI
+}
+
+// Prepare a new AWS & DynamoDB session, then configure it.
+var TestAws *AmazonWebServices
+
+func init() {
+	region := os.Getenv("AWS_REGION")
+	var Aws *AmazonWebServices = new(AmazonWebServices)
+	Aws.Config = &aws.Config{Region: aws.String(region)}
+	var err error
+	Aws.Session, err = session.NewSession(Aws.Config)
+	if err != nil {
+		// Logs error on Amazon CloudWatch. It's sysadmin's duty to handle it.
+		fmt.Println(fmt.Sprintf("Failed to connect to AWS: %s", err.Error()))
+	} else {
+		var svc *dynamodb.DynamoDB = dynamodb.New(Aws.Session)
+		Aws.DynamoDB = dynamodbiface.DynamoDBAPI(svc)
+	}
+	// Instantiate a global session in TestAws
+	TestAws = Aws
+}
+
+// Preparing DynamoDB Session and Calling DB's PutItem function inside.
+// Replacing must never create a new device model, so the id has to exist already.
+func (self *AmazonWebServices) Replace(item map[string]*dynamodb.AttributeValue) (*dynamodb.PutItemOutput, error) {
+	// Get table name from OS's environment
+	tableName := aws.String(os.Getenv("DEVICE_MODELS_TABLE_NAME"))
+	var input = &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           tableName,
+		ConditionExpression: aws.String("attribute_exists(id)"),
+	}
+	// Calling either PutItem function of interface, defined in updateDeviceModel_test.go file, or api with the input we've provided.
+	result, err := self.DynamoDB.PutItem(input)
+	return result, err
+}
+
+// The handler function which will be first started from main function.
+func UpdateDeviceModel(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// The id which user has sent through PUT method.
+	id := request.PathParameters["modelId"]
+
+	// If no id have been provided, return HTTP error code 404.
+	if id == "" {
+		return events.APIGatewayProxyResponse{
+			Body:       "Missing field : modelId",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// First & foremost we have to validate user input.
+	UpdatedModel, err := ValidateInputs(id, request)
+	// if inputs are not suitable, return HTTP error code 400.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       err.Error(),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// Serialization/Encoding "UpdatedModel" in "item" for using in DynamoDB functions.
+	item, _ := dynamodbattribute.MarshalMap(UpdatedModel)
+
+	_, err = TestAws.Replace(item)
+
+	// The device model does not exist, return HTTP error code 404.
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return events.APIGatewayProxyResponse{
+			Body:       "Desired device model not found.",
+			StatusCode: 404,
+		}, nil
+	}
+
+	// If internal database errors occurred, return HTTP error code 500.
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			Body:       "Internal Server Error\nDatabase error.",
+			StatusCode: 500,
+		}, nil
+	}
+
+	// Serialization/Encoding "UpdatedModel" to JSON.
+	jsonResponse, _ := json.Marshal(UpdatedModel)
+	return events.APIGatewayProxyResponse{
+		Body: string(jsonResponse),
+		// Everything looks fine, return HTTP 200
+		StatusCode: 200,
+	}, nil
+} // End of UpdateDeviceModel function
+
+// The id in the body can be left out, as it's already in the path.
+func ValidateInputs(id string, request events.APIGatewayProxyRequest) (types.DeviceModel, error) {
+	// De-serialize "request.Body" which is in JSON format into "UpdatedModel" in Go object.
+	UpdatedModel, err := types.ParseDeviceModel(request.Body)
+	if err != nil {
+		return types.DeviceModel{}, err
+	}
+
+	if UpdatedModel.ID == "" {
+		UpdatedModel.ID = id
+	}
+	if UpdatedModel.ID != id {
+		return types.DeviceModel{}, errors.New("Wrong id: The id in the body does not match the id in the path.")
+	}
+
+	if err = UpdatedModel.Validate(); err != nil {
+		return types.DeviceModel{}, err
+	}
+
+	// Everything looks fine, return UpdatedModel in Go struct.
+	return UpdatedModel, nil
+} // End of ValidateInputs function.
+
+func main() {
+	lambda.Start(UpdateDeviceModel)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"testing"
+)
+
+type TestCase struct {
+	Name               string
+	Request            events.APIGatewayProxyRequest
+	ExpectedBody       string
+	ExpectedStatusCode int
+}
+
+// Mocking DynamoDB through dynamodbiface.
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	// Last input the mocked PutItem function has received.
+	Input *dynamodb.PutItemInput
+}
+
+// Custom PutItem function for overriding the PutItem of updateDeviceModel.go for using in test scenarios.
+// Only "model_test" exists on the mocked DB.
+func (self *MockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	self.Input = input
+	if *input.Item["id"].S != "model_test" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	return new(dynamodb.PutItemOutput), nil
+}
+
+// UpdateDeviceModel function in updateDeviceModel.go signature: input: (request events.APIGatewayProxyRequest), output: (events.APIGatewayProxyResponse, error)
+func TestUpdateDeviceModel(t *testing.T) {
+	mock := &MockDynamoDB{}
+	TestAws = &AmazonWebServices{DynamoDB: mock}
+
+	testCases := []TestCase{
+		{
+			Name:               "** Testing: Body id disagrees with path id. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "model_test"}, Body: "{\"id\":\"other_id\",\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\"}"},
+			ExpectedBody:       "Wrong id: The id in the body does not match the id in the path.",
+			ExpectedStatusCode: 400,
+		},
+
+		{
+			Name:               "** Testing: Device model does not exist. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "not_existed"}, Body: "{\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\"}"},
+			ExpectedBody:       "Desired device model not found.",
+			ExpectedStatusCode: 404,
+		},
+
+		{
+			Name:               "** Testing: Id taken from path. **",
+			Request:            events.APIGatewayProxyRequest{PathParameters: map[string]string{"modelId": "model_test"}, Body: "{\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\"}"},
+			ExpectedBody:       "{\"id\":\"model_test\",\"manufacturer\":\"testManufacturer\",\"modelName\":\"testModelName\"}",
+			ExpectedStatusCode: 200,
+		},
+	}
+
+	for _, test := range testCases {
+		// Executing each test cases scenario.
+		response, _ := UpdateDeviceModel(test.Request)
+		if response.StatusCode != test.ExpectedStatusCode || response.Body != test.ExpectedBody {
+			t.Errorf("%s \n \t<expected error-code: %d> <resulted error-code: %d> \n \t<expected body: %s> <resulted body: %s>", test.Name, test.ExpectedStatusCode, response.StatusCode, test.ExpectedBody, response.Body)
+		}
+	}
+
+	// Replacing must never create a new device model.
+	if *mock.Input.ConditionExpression != "attribute_exists(id)" {
+		t.Errorf("** Testing: Replace-only put. ** \n \t<resulted input: \n%s>", mock.Input.GoString())
+	}
+} // End of TestUpdateDeviceModel function